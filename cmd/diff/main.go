@@ -0,0 +1,30 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"pipeline"
+)
+
+func main() {
+	color := false
+	refs := []string{}
+	for _, arg := range os.Args[1:] {
+		if arg == "--color" {
+			color = true
+			continue
+		}
+		refs = append(refs, arg)
+	}
+	if len(refs) == 0 {
+		fmt.Fprintln(os.Stderr, "Sorry ... maybe you forgot the memos?")
+		os.Exit(1)
+	}
+	p := pipeline.Diff(refs, "", color)
+	p.Output = os.Stdout
+	p.Stdout()
+	if p.Error.Err != nil {
+		fmt.Fprintln(os.Stderr, p.Error.Error())
+		os.Exit(1)
+	}
+}
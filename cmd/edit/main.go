@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"pipeline"
+	"strings"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, "Sorry ... maybe you forgot the memo?")
+		os.Exit(1)
+	}
+	p := pipeline.Edit(os.Args[1], "", runEditor)
+	p.Output = os.Stderr
+	p.Stdout()
+	if p.Error.Err != nil {
+		fmt.Fprintln(os.Stderr, p.Error.Error())
+		os.Exit(1)
+	}
+	fmt.Fprintln(os.Stderr, "✓ edited")
+}
+
+// runEditor ... opens the file in $EDITOR, vi if there is none
+func runEditor(path string) error {
+	editor := strings.Fields(os.Getenv("EDITOR"))
+	if len(editor) == 0 {
+		editor = []string{"vi"}
+	}
+	cmd := exec.Command(editor[0], append(editor[1:], path)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"pipeline"
+	"strings"
+)
+
+func main() {
+	yes, dryRun := false, false
+	refs := []string{}
+	for _, arg := range os.Args[1:] {
+		switch arg {
+		case "--yes":
+			yes = true
+		case "--dry-run":
+			dryRun = true
+		default:
+			refs = append(refs, arg)
+		}
+	}
+	confirm := askTerminal
+	if yes {
+		confirm = nil
+	}
+	p := pipeline.Forget(os.Stdin, refs, "", dryRun, confirm)
+	p.Output = os.Stdout
+	p.Stdout()
+	if p.Error.Err != nil {
+		fmt.Fprintln(os.Stderr, p.Error.Error())
+		os.Exit(1)
+	}
+	if !dryRun {
+		fmt.Fprintln(os.Stderr, "✓ forgotten")
+	}
+}
+
+// askTerminal ... stdin may be the pipe, so the answer comes from the terminal
+func askTerminal(list []string) bool {
+	tty, err := os.Open("/dev/tty")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "✘ no terminal to confirm, use --yes")
+		return false
+	}
+	defer tty.Close()
+	for _, line := range list {
+		fmt.Fprintf(os.Stderr, "  %s\n", line)
+	}
+	fmt.Fprintf(os.Stderr, "? forget %d memos for good [y/N] ", len(list))
+	answer, _ := bufio.NewReader(tty).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
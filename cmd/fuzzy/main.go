@@ -0,0 +1,27 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"pipeline"
+)
+
+func main() {
+	args := os.Args[1:]
+	tags := false
+	if len(args) > 0 && args[0] == "--tags" {
+		tags = true
+		args = args[1:]
+	}
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "Sorry ... maybe you forgot any word?")
+		os.Exit(1)
+	}
+	p := pipeline.Fuzzy(os.Stdin, "", tags, args...)
+	p.Output = os.Stdout
+	p.Stdout()
+	if p.Error.Err != nil {
+		fmt.Fprintln(os.Stderr, p.Error.Error())
+		os.Exit(1)
+	}
+}
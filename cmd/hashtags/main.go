@@ -0,0 +1,22 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"pipeline"
+)
+
+func main() {
+	what := ""
+	if len(os.Args) > 1 {
+		what = os.Args[1]
+	}
+	p := pipeline.Hashtags(os.Stdin, what, "")
+	p.Output = os.Stdout
+	p.Stdout()
+	if p.Error.Err != nil {
+		fmt.Fprintln(os.Stderr, p.Error.Error())
+		os.Exit(1)
+	}
+	fmt.Fprintln(os.Stderr, "✓ hashtagged")
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"pipeline"
+)
+
+func main() {
+	opts := pipeline.MatchOptions{}
+	pattern := ""
+	for _, arg := range os.Args[1:] {
+		switch arg {
+		case "--tags":
+			opts.Field = "tags"
+		case "--modified":
+			opts.Field = "modified"
+		case "-v", "--invert":
+			opts.Invert = true
+		case "-o", "--only-matching":
+			opts.OnlyMatching = true
+		default:
+			pattern = arg
+		}
+	}
+	if pattern == "" {
+		fmt.Fprintln(os.Stderr, "Sorry ... maybe you forgot the regular expression?")
+		os.Exit(1)
+	}
+	p := pipeline.Match(os.Stdin, pattern, opts, "")
+	p.Output = os.Stdout
+	p.Stdout()
+	if p.Error.Err != nil {
+		fmt.Fprintln(os.Stderr, p.Error.Error())
+		os.Exit(1)
+	}
+}
@@ -7,7 +7,7 @@ import (
 )
 
 func main() {
-	p := pipeline.ToJSON(os.Stdin)
+	p := pipeline.ToJSON(os.Stdin, "")
 	p.Output = os.Stdout
 	p.Stdout()
 	if p.Error.Err != nil {
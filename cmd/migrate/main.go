@@ -0,0 +1,16 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"pipeline"
+)
+
+func main() {
+	migrated, err := pipeline.Migrate("")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "✘ error ... %q\n", err)
+		os.Exit(1)
+	}
+	fmt.Fprintf(os.Stderr, "✓ migrated ... %d entries\n", migrated)
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"pipeline"
+	"sort"
+	"strings"
+)
+
+func main() {
+	args := os.Args[1:]
+	if len(args) > 0 {
+		switch args[0] {
+		case "--save":
+			save(args[1:])
+			return
+		case "--list":
+			list()
+			return
+		case "--remove":
+			if len(args) != 2 {
+				fmt.Fprintln(os.Stderr, "Sorry ... maybe you forgot the name?")
+				os.Exit(1)
+			}
+			err := pipeline.RemoveSearch(strings.TrimPrefix(args[1], "@"), "")
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "✘ error ... %q\n", err)
+				os.Exit(1)
+			}
+			fmt.Fprintf(os.Stderr, "✓ removed ... @%s\n", strings.TrimPrefix(args[1], "@"))
+			return
+		}
+	}
+	p := pipeline.Query("", args...)
+	p.Output = os.Stdout
+	p.Stdout()
+	if p.Error.Err != nil {
+		fmt.Fprintln(os.Stderr, p.Error.Error())
+		os.Exit(1)
+	}
+}
+
+func save(args []string) {
+	if len(args) < 2 {
+		fmt.Fprintln(os.Stderr, "Sorry ... maybe you forgot the name or the expression?")
+		os.Exit(1)
+	}
+	name, description := strings.TrimPrefix(args[0], "@"), ""
+	args = args[1:]
+	if args[0] == "--description" {
+		if len(args) < 3 {
+			fmt.Fprintln(os.Stderr, "Sorry ... maybe you forgot the description or the expression?")
+			os.Exit(1)
+		}
+		description = args[1]
+		args = args[2:]
+	}
+	// The expression is saved as given, so it must come quoted as one
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "Sorry ... maybe you forgot to quote the expression?")
+		os.Exit(1)
+	}
+	if strings.HasPrefix(args[0], "--") {
+		fmt.Fprintf(os.Stderr, "Sorry ... %s is no option of --save\n", args[0])
+		os.Exit(1)
+	}
+	err := pipeline.SaveSearch(name, args[0], description, "")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "✘ error ... %q\n", err)
+		os.Exit(1)
+	}
+	fmt.Fprintf(os.Stderr, "✓ saved ... @%s\n", name)
+}
+
+func list() {
+	cfg, err := pipeline.LoadConfig("")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "✘ error ... %q\n", err)
+		os.Exit(1)
+	}
+	names := []string{}
+	for name := range cfg.Searches {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		search := cfg.Searches[name]
+		if search.Description == "" {
+			fmt.Printf("@%s  %s\n", name, search.Query)
+			continue
+		}
+		fmt.Printf("@%s  %s  (%s)\n", name, search.Query, search.Description)
+	}
+}
@@ -0,0 +1,16 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"pipeline"
+)
+
+func main() {
+	indexed, err := pipeline.Reindex("")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "✘ error ... %q\n", err)
+		os.Exit(1)
+	}
+	fmt.Fprintf(os.Stderr, "✓ reindexed ... %d memos\n", indexed)
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"pipeline"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, "Sorry ... maybe you forgot the memo?")
+		os.Exit(1)
+	}
+	p := pipeline.Restore(os.Args[1:], "")
+	p.Output = os.Stdout
+	p.Stdout()
+	if p.Error.Err != nil {
+		fmt.Fprintln(os.Stderr, p.Error.Error())
+		os.Exit(1)
+	}
+	fmt.Fprintln(os.Stderr, "✓ restored")
+}
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"pipeline"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, "Sorry ... maybe you forgot any term?")
+		os.Exit(1)
+	}
+	p := pipeline.Search(os.Stdin, "", os.Args[1:]...)
+	p.Output = os.Stdout
+	p.Stdout()
+	if p.Error.Err != nil {
+		fmt.Fprintln(os.Stderr, p.Error.Error())
+		os.Exit(1)
+	}
+}
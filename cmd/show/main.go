@@ -0,0 +1,25 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"pipeline"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, "Sorry ... maybe you forgot the memo?")
+		os.Exit(1)
+	}
+	what := "short"
+	if len(os.Args) > 2 {
+		what = os.Args[2]
+	}
+	p := pipeline.Show(os.Args[1], what, "")
+	p.Output = os.Stdout
+	p.Stdout()
+	if p.Error.Err != nil {
+		fmt.Fprintln(os.Stderr, p.Error.Error())
+		os.Exit(1)
+	}
+}
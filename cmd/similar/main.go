@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"pipeline"
+	"strconv"
+)
+
+func main() {
+	ref, limit := "", 10
+	args := os.Args[1:]
+	for n := 0; n < len(args); n++ {
+		switch args[n] {
+		case "--limit":
+			if n+1 == len(args) {
+				fmt.Fprintln(os.Stderr, "Sorry ... maybe you forgot the number after --limit?")
+				os.Exit(1)
+			}
+			n++
+			l, err := strconv.Atoi(args[n])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Sorry ... %q is no number\n", args[n])
+				os.Exit(1)
+			}
+			if l < 1 {
+				fmt.Fprintf(os.Stderr, "Sorry ... %d is no limit, take 1 or more\n", l)
+				os.Exit(1)
+			}
+			limit = l
+		default:
+			ref = args[n]
+		}
+	}
+	// Without ref the memo comes by the pipe
+	if stat, err := os.Stdin.Stat(); ref == "" && err == nil && stat.Mode()&os.ModeCharDevice != 0 {
+		fmt.Fprintln(os.Stderr, "Sorry ... maybe you forgot the ref or the memo from the pipe?")
+		os.Exit(1)
+	}
+	p := pipeline.Similar(os.Stdin, ref, limit, "")
+	p.Output = os.Stdout
+	p.Stdout()
+	if p.Error.Err != nil {
+		fmt.Fprintln(os.Stderr, p.Error.Error())
+		os.Exit(1)
+	}
+}
@@ -4,14 +4,29 @@ import (
 	"fmt"
 	"os"
 	"pipeline"
+	"strconv"
 )
 
 func main() {
-	param := "short"
-	if len(os.Args) > 1 {
-		param = os.Args[1]
+	param, limit := "short", 0
+	args := os.Args[1:]
+	for n := 0; n < len(args); n++ {
+		switch args[n] {
+		case "--limit":
+			if n+1 < len(args) {
+				n++
+				l, err := strconv.Atoi(args[n])
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Sorry ... %q is no number\n", args[n])
+					os.Exit(1)
+				}
+				limit = l
+			}
+		default:
+			param = args[n]
+		}
 	}
-	p := pipeline.Stdout(os.Stdin, param)
+	p := pipeline.Stdout(os.Stdin, param, limit, "")
 	p.Output = os.Stdout
 	p.Stdout()
 	if p.Error.Err != nil {
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"pipeline"
+	"strconv"
+)
+
+func main() {
+	what, limit := "", 10
+	if len(os.Args) > 1 {
+		what = os.Args[1]
+		if n, err := strconv.Atoi(what); err == nil {
+			what, limit = "", n
+		}
+	}
+	p := pipeline.Suggest(os.Stdin, what, limit, "")
+	p.Output = os.Stdout
+	p.Stdout()
+	if p.Error.Err != nil {
+		fmt.Fprintln(os.Stderr, p.Error.Error())
+		os.Exit(1)
+	}
+}
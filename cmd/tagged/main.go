@@ -11,7 +11,7 @@ func main() {
 		fmt.Fprintln(os.Stderr, "Sorry ... maybe you forgot any tag?")
 		os.Exit(1)
 	}
-	p := pipeline.Tagged(os.Stdin, os.Args[1:]...)
+	p := pipeline.Tagged(os.Stdin, "", os.Args[1:]...)
 	p.Output = os.Stdout
 	p.Stdout()
 	if p.Error.Err != nil {
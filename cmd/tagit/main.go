@@ -1,9 +1,12 @@
 package main
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 	"os"
 	"pipeline"
+	"strings"
 )
 
 func main() {
@@ -11,13 +14,21 @@ func main() {
 		fmt.Fprintln(os.Stderr, "Sorry ... maybe you forgot the tag?")
 		os.Exit(1)
 	}
-	tag := os.Args[1]
-	p := pipeline.TagIt(os.Stdin, tag)
-	p.Output = os.Stdout
+	tags := os.Args[1:]
+	p := pipeline.TagIt(os.Stdin, "", tags...)
+	// Report the tags as they got stored, not as they were typed
+	tagged := &bytes.Buffer{}
+	p.Output = io.MultiWriter(os.Stdout, tagged)
 	p.Stdout()
 	if p.Error.Err != nil {
-		fmt.Fprintln(os.Stderr, p.Error.Err.Error())
+		fmt.Fprintln(os.Stderr, p.Error.Error())
 		os.Exit(1)
 	}
-	fmt.Fprintf(os.Stderr, "✓ tagged ... %s\n", tag)
+	memo := pipeline.Memo{}
+	err := pipeline.Unmarshal(tagged, &memo)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "✘ error ... %q\n", err)
+		os.Exit(1)
+	}
+	fmt.Fprintf(os.Stderr, "✓ tagged ... %s\n", strings.Join(memo.Tags, ", "))
 }
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"pipeline"
+	"sort"
+)
+
+func main() {
+	args := os.Args[1:]
+	if len(args) > 0 {
+		switch args[0] {
+		case "rename":
+			rename(args[1:])
+			return
+		case "alias":
+			alias(args[1:])
+			return
+		}
+	}
+	what, sortBy, format := "list", "name", "text"
+	for n := 0; n < len(args); n++ {
+		switch args[n] {
+		case "--json":
+			format = "json"
+		case "--sort":
+			if n+1 < len(args) {
+				n++
+				sortBy = args[n]
+			}
+		default:
+			what = args[n]
+		}
+	}
+	// Without pipe the whole index is meant
+	var rd io.Reader = os.Stdin
+	if stat, err := os.Stdin.Stat(); err == nil && stat.Mode()&os.ModeCharDevice != 0 {
+		f := pipeline.From(pipeline.PeriodAll, "")
+		if f.Error.Err != nil {
+			fmt.Fprintln(os.Stderr, f.Error.Error())
+			os.Exit(1)
+		}
+		rd = f.Reader
+	}
+	p := pipeline.Tags(rd, what, sortBy, format)
+	p.Output = os.Stdout
+	p.Stdout()
+	if p.Error.Err != nil {
+		fmt.Fprintln(os.Stderr, p.Error.Error())
+		os.Exit(1)
+	}
+}
+
+func rename(args []string) {
+	if len(args) != 2 {
+		fmt.Fprintln(os.Stderr, "Sorry ... maybe you forgot the old or the new tag?")
+		os.Exit(1)
+	}
+	renamed, err := pipeline.RenameTag(args[0], args[1], "")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "✘ error ... %q\n", err)
+		os.Exit(1)
+	}
+	fmt.Fprintf(os.Stderr, "✓ renamed ... %d memos\n", renamed)
+}
+
+func alias(args []string) {
+	switch len(args) {
+	case 0:
+		cfg, err := pipeline.LoadConfig("")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "✘ error ... %q\n", err)
+			os.Exit(1)
+		}
+		synonyms := []string{}
+		for synonym := range cfg.Aliases {
+			synonyms = append(synonyms, synonym)
+		}
+		sort.Strings(synonyms)
+		for _, synonym := range synonyms {
+			fmt.Printf("%s -> %s\n", synonym, cfg.Aliases[synonym])
+		}
+	case 2:
+		err := pipeline.SetAlias(args[0], args[1], "")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "✘ error ... %q\n", err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "✓ alias ... %s -> %s\n", args[0], args[1])
+	default:
+		fmt.Fprintln(os.Stderr, "Sorry ... maybe you forgot the synonym or the canonical tag?")
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"pipeline"
+	"time"
+)
+
+func main() {
+	what := "list"
+	if len(os.Args) > 1 {
+		what = os.Args[1]
+	}
+	if what == "empty" {
+		var olderThan time.Duration
+		switch {
+		case len(os.Args) == 3 && os.Args[2] == "--all":
+		case len(os.Args) == 4 && os.Args[2] == "--older-than":
+			age, err := pipeline.ParseAge(os.Args[3])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "✘ error ... %q\n", err)
+				os.Exit(1)
+			}
+			olderThan = age
+		default:
+			fmt.Fprintln(os.Stderr, "Sorry ... maybe you forgot --older-than <age> or --all?")
+			os.Exit(1)
+		}
+		purged, err := pipeline.EmptyTrash(olderThan, "")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "✘ error ... %q\n", err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "✓ purged ... %d memos\n", purged)
+		return
+	}
+	p := pipeline.Trash(what, "")
+	p.Output = os.Stdout
+	p.Stdout()
+	if p.Error.Err != nil {
+		fmt.Fprintln(os.Stderr, p.Error.Error())
+		os.Exit(1)
+	}
+}
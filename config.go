@@ -0,0 +1,207 @@
+package pipeline
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const configFile = "config.json"
+
+type (
+	// Config ... settings of the store, kept next to the index
+	Config struct {
+		KeepCase bool                   // tags are case-folded unless set
+		Aliases  map[string]string      // synonyms of tags and their canonical tag
+		Hashtags HashtagConfig          // hashtags in the content of new memos
+		Searches map[string]SavedSearch // queries by their name, used as @name
+	}
+
+	// SavedSearch ... a query expression kept for reuse
+	SavedSearch struct {
+		Query       string
+		Description string `json:",omitempty"`
+	}
+)
+
+//////////////////////////////////////////////////////////
+// LoadConfig ... reads the config of the store, where the
+// index at filePath lives, a missing config means defaults
+//////////////////////////////////////////////////////////
+func LoadConfig(filePath string) (Config, error) {
+	if filePath == "" {
+		filePath = TakeMeHome(basePath + string(os.PathSeparator) + indexFile)
+	}
+	cfg := Config{}
+	fi, err := os.Open(filepath.Join(filepath.Dir(filePath), configFile))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return cfg, nil
+		}
+		return cfg, err
+	}
+	defer fi.Close()
+	err = Unmarshal(fi, &cfg)
+	if err != nil {
+		return cfg, err
+	}
+	// Aliases are edited by hand, so they get normalised, too
+	plain := Config{KeepCase: cfg.KeepCase}
+	aliases := make(map[string]string, len(cfg.Aliases))
+	for synonym, canonical := range cfg.Aliases {
+		synonym, err = NormalizeTag(synonym, plain)
+		if err != nil {
+			return cfg, err
+		}
+		aliases[synonym], err = NormalizeTag(canonical, plain)
+		if err != nil {
+			return cfg, err
+		}
+	}
+	cfg.Aliases = aliases
+	return cfg, nil
+}
+
+//////////////////////////////////////////////////////////
+// SaveConfig ... writes the config of the store, where the
+// index at filePath lives
+//////////////////////////////////////////////////////////
+func SaveConfig(cfg Config, filePath string) error {
+	if filePath == "" {
+		filePath = TakeMeHome(basePath + string(os.PathSeparator) + indexFile)
+	}
+	r, err := Marshal(cfg)
+	if err != nil {
+		return err
+	}
+	// Aliases and searches are kept by hand, never leave them half written
+	path := filepath.Join(filepath.Dir(filePath), configFile)
+	fi, err := os.CreateTemp(filepath.Dir(path), configFile+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(fi.Name())
+	err = fi.Chmod(0o644)
+	if err != nil {
+		fi.Close()
+		return err
+	}
+	_, err = fi.ReadFrom(r)
+	if err != nil {
+		fi.Close()
+		return err
+	}
+	err = fi.Close()
+	if err != nil {
+		return err
+	}
+	return os.Rename(fi.Name(), path)
+}
+
+//////////////////////////////////////////////////////////
+// SetAlias ... makes synonym an alias of canonical in the
+// store, where the index at filePath lives
+//////////////////////////////////////////////////////////
+func SetAlias(synonym, canonical, filePath string) error {
+	cfg, err := LoadConfig(filePath)
+	if err != nil {
+		return err
+	}
+	plain := Config{KeepCase: cfg.KeepCase}
+	synonym, err = NormalizeTag(synonym, plain)
+	if err != nil {
+		return err
+	}
+	canonical, err = NormalizeTag(canonical, plain)
+	if err != nil {
+		return err
+	}
+	if synonym == canonical {
+		return fmt.Errorf("tag %q can't be an alias of itself", synonym)
+	}
+	// Aliases of aliases would make chains or even cycles
+	if other, exist := cfg.Aliases[canonical]; exist {
+		return fmt.Errorf("tag %q is an alias of %q itself, take that one", canonical, other)
+	}
+	for other, target := range cfg.Aliases {
+		if target == synonym {
+			return fmt.Errorf("tag %q is the canonical tag of %q, it can't be an alias", synonym, other)
+		}
+	}
+	if cfg.Aliases == nil {
+		cfg.Aliases = make(map[string]string)
+	}
+	cfg.Aliases[synonym] = canonical
+	return SaveConfig(cfg, filePath)
+}
+
+//////////////////////////////////////////////////////////
+// SaveSearch ... keeps a query expression by name in the
+// store, where the index at filePath lives
+//////////////////////////////////////////////////////////
+func SaveSearch(name, expr, description, filePath string) error {
+	if name == "" || strings.IndexFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r) && r != '-' && r != '_'
+	}) >= 0 {
+		return fmt.Errorf("invalid name %q for a search, take letters, digits, - and _", name)
+	}
+	if strings.TrimSpace(expr) == "" {
+		return fmt.Errorf("search @%s needs an expression", name)
+	}
+	// Searches within searches aren't expanded, they'd be words
+	for _, word := range strings.Fields(expr) {
+		if strings.HasPrefix(word, "@") {
+			return fmt.Errorf("search @%s must not refer to %s, saved searches don't nest", name, word)
+		}
+	}
+	cfg, err := LoadConfig(filePath)
+	if err != nil {
+		return err
+	}
+	// Broken searches shouldn't wait for their first use
+	_, err = ParseQuery(expr, cfg, time.Now())
+	if err != nil {
+		return err
+	}
+	if cfg.Searches == nil {
+		cfg.Searches = make(map[string]SavedSearch)
+	}
+	cfg.Searches[name] = SavedSearch{Query: expr, Description: description}
+	return SaveConfig(cfg, filePath)
+}
+
+//////////////////////////////////////////////////////////
+// RemoveSearch ... drops a saved search from the store,
+// where the index at filePath lives
+//////////////////////////////////////////////////////////
+func RemoveSearch(name, filePath string) error {
+	cfg, err := LoadConfig(filePath)
+	if err != nil {
+		return err
+	}
+	if _, exist := cfg.Searches[name]; !exist {
+		return fmt.Errorf("search @%s does not exist", name)
+	}
+	delete(cfg.Searches, name)
+	return SaveConfig(cfg, filePath)
+}
+
+// canonical ... replaces a synonym by its canonical tag, for
+// hierarchical tags the longest aliased ancestor counts
+func (c Config) canonical(tag string) string {
+	for prefix := tag; prefix != ""; {
+		if canonical, exist := c.Aliases[prefix]; exist {
+			return canonical + tag[len(prefix):]
+		}
+		i := strings.LastIndex(prefix, tagLevel)
+		if i < 0 {
+			break
+		}
+		prefix = prefix[:i]
+	}
+	return tag
+}
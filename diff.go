@@ -0,0 +1,322 @@
+package pipeline
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// Lines of context around each change
+	diffContext = 3
+
+	colorRed   = "\033[31m"
+	colorGreen = "\033[32m"
+	colorCyan  = "\033[36m"
+	colorReset = "\033[0m"
+)
+
+type (
+	// diffOp ... a single line of an edit script, kind is one of ' ', '-' or '+'
+	diffOp struct {
+		kind byte
+		line string
+		a, b int // line numbers in both texts, counting from 0
+	}
+)
+
+//////////////////////////////////////////////////////////
+// Diff ... compares two memos or two revisions of a memo
+// as unified diff of their content plus the differences
+// of their tags
+// used by diff
+//////////////////////////////////////////////////////////
+func Diff(refs []string, filePath string, color bool) *Pipeline {
+	// Help wanted?
+	if len(refs) > 0 && refs[0] == "help" {
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "DIFF is part of memo's famous toolbox")
+		fmt.Fprintln(os.Stderr, "-------------------------------------")
+		fmt.Fprintln(os.Stderr, "Usage: diff [--color] <ref>[@<revision>] [<ref>[@<revision>]]")
+		fmt.Fprintln(os.Stderr, "       diff help")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "It prints the differences between two memos as unified diff, the")
+		fmt.Fprintln(os.Stderr, "tags first and the content afterwards. Revisions are selected by")
+		fmt.Fprintln(os.Stderr, "@<n>, see history for the list. With a single reference ...")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "  ✓ <ref> ... compares the prior revision with the current memo")
+		fmt.Fprintln(os.Stderr, "  ✓ <ref>@<n> ... compares the revision with the current memo")
+		fmt.Fprintln(os.Stderr)
+		toolbox()
+		os.Exit(0)
+	}
+	if len(refs) < 1 || len(refs) > 2 {
+		return &Pipeline{
+			Error: MaskedError{
+				Prefix: "✘ error ... ",
+				Err:    errors.New("diff needs one or two memos"),
+			},
+		}
+	}
+	if filePath == "" {
+		filePath = TakeMeHome(basePath + string(os.PathSeparator) + indexFile)
+	}
+	idx := NewIndex()
+	err := idx.Load(filePath)
+	if err != nil {
+		return &Pipeline{
+			Error: MaskedError{
+				Prefix: "✘ error ... ",
+				Err:    err,
+			},
+		}
+	}
+	base := filepath.Dir(filePath)
+	prefixes := idx.Prefixes()
+	// Resolve both sides to memos, a single one is compared with itself
+	names := []string{}
+	memos := []Memo{}
+	for _, ref := range refs {
+		ref, rev, err := SplitRevision(ref)
+		if err != nil {
+			return &Pipeline{
+				Error: MaskedError{
+					Prefix: "✘ error ... ",
+					Err:    err,
+				},
+			}
+		}
+		key, err := idx.Resolve(ref)
+		if err != nil {
+			return &Pipeline{
+				Error: MaskedError{
+					Prefix: "✘ error ... ",
+					Err:    err,
+				},
+			}
+		}
+		if len(refs) == 1 && rev == 0 {
+			revs, err := revisions(base, key)
+			if err != nil {
+				return &Pipeline{
+					Error: MaskedError{
+						Prefix: "✘ error ... ",
+						Err:    err,
+					},
+				}
+			}
+			if len(revs) == 0 {
+				return &Pipeline{
+					Error: MaskedError{
+						Prefix: "✘ error ... ",
+						Err:    fmt.Errorf("memo %s has no prior revision", key),
+					},
+				}
+			}
+			rev = len(revs)
+		}
+		memo, err := revision(base, key, idx.Find(key), rev)
+		if err != nil {
+			return &Pipeline{
+				Error: MaskedError{
+					Prefix: "✘ error ... ",
+					Err:    err,
+				},
+			}
+		}
+		name := prefixes[key]
+		if rev > 0 {
+			name = fmt.Sprintf("%s@%d", name, rev)
+		}
+		names = append(names, name)
+		memos = append(memos, memo)
+		if len(refs) == 1 {
+			memo, err := revision(base, key, idx.Find(key), 0)
+			if err != nil {
+				return &Pipeline{
+					Error: MaskedError{
+						Prefix: "✘ error ... ",
+						Err:    err,
+					},
+				}
+			}
+			names = append(names, prefixes[key])
+			memos = append(memos, memo)
+		}
+	}
+	paint := func(code, line string) string {
+		if !color {
+			return line
+		}
+		return code + line + colorReset
+	}
+	buf := &bytes.Buffer{}
+	fmt.Fprintln(buf, paint(colorCyan, fmt.Sprintf("--- %s %s", names[0], memos[0].Modified)))
+	fmt.Fprintln(buf, paint(colorCyan, fmt.Sprintf("+++ %s %s", names[1], memos[1].Modified)))
+	// Tags are a set, so only the differences count
+	removed, added := tagDiff(memos[0].Tags, memos[1].Tags)
+	for _, tag := range removed {
+		fmt.Fprintln(buf, paint(colorRed, "-Tag: "+tag))
+	}
+	for _, tag := range added {
+		fmt.Fprintln(buf, paint(colorGreen, "+Tag: "+tag))
+	}
+	for _, line := range unifiedDiff(splitLines(memos[0].Content), splitLines(memos[1].Content), diffContext) {
+		switch line[0] {
+		case '-':
+			line = paint(colorRed, line)
+		case '+':
+			line = paint(colorGreen, line)
+		case '@':
+			line = paint(colorCyan, line)
+		}
+		fmt.Fprintln(buf, line)
+	}
+	return &Pipeline{
+		Reader: buf,
+	}
+}
+
+// tagDiff ... lists tags only in a and tags only in b, both sorted
+func tagDiff(a, b []string) (removed, added []string) {
+	inA := make(map[string]bool)
+	for _, tag := range a {
+		inA[tag] = true
+	}
+	inB := make(map[string]bool)
+	for _, tag := range b {
+		inB[tag] = true
+	}
+	removed = IndexEntry{Tags: inA}.SortedTags()
+	added = IndexEntry{Tags: inB}.SortedTags()
+	removed = filterTags(removed, func(tag string) bool { return !inB[tag] })
+	added = filterTags(added, func(tag string) bool { return !inA[tag] })
+	return removed, added
+}
+
+// filterTags ... keeps the tags, for which keep is true
+func filterTags(tags []string, keep func(tag string) bool) []string {
+	kept := []string{}
+	for _, tag := range tags {
+		if keep(tag) {
+			kept = append(kept, tag)
+		}
+	}
+	return kept
+}
+
+// splitLines ... splits content into lines without the final line break
+func splitLines(content string) []string {
+	if content == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+}
+
+// editScript ... calculates the shortest way from a to b by the
+// longest common subsequence of lines
+func editScript(a, b []string) []diffOp {
+	// lcs[i][j] is the length of the LCS of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			switch {
+			case a[i] == b[j]:
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				lcs[i][j] = lcs[i+1][j]
+			default:
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	ops := []diffOp{}
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i], i, j})
+			i++
+			j++
+		case j == len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, diffOp{'-', a[i], i, j})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j], i, j})
+			j++
+		}
+	}
+	return ops
+}
+
+// unifiedDiff ... formats the changes from a to b as hunks with
+// context lines, no changes make no lines
+func unifiedDiff(a, b []string, context int) []string {
+	ops := editScript(a, b)
+	lines := []string{}
+	for start := 0; start < len(ops); {
+		// Find the next change
+		first := start
+		for first < len(ops) && ops[first].kind == ' ' {
+			first++
+		}
+		if first == len(ops) {
+			break
+		}
+		// Extend the hunk as long as changes are close enough
+		last := first
+		for n := first; n < len(ops); n++ {
+			if ops[n].kind != ' ' {
+				last = n
+				continue
+			}
+			if n-last > 2*context {
+				break
+			}
+		}
+		from := first - context
+		if from < start {
+			from = start
+		}
+		if from < 0 {
+			from = 0
+		}
+		to := last + context + 1
+		if to > len(ops) {
+			to = len(ops)
+		}
+		countA, countB := 0, 0
+		for _, op := range ops[from:to] {
+			if op.kind != '+' {
+				countA++
+			}
+			if op.kind != '-' {
+				countB++
+			}
+		}
+		lines = append(lines, fmt.Sprintf("@@ -%s +%s @@",
+			hunkRange(ops[from].a, countA), hunkRange(ops[from].b, countB)))
+		for _, op := range ops[from:to] {
+			lines = append(lines, string(op.kind)+op.line)
+		}
+		start = to
+	}
+	return lines
+}
+
+// hunkRange ... formats the start and length of a hunk like diff -u does
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
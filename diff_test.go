@@ -0,0 +1,58 @@
+package pipeline_test
+
+import (
+	"bytes"
+	"os"
+	"pipeline"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDiffRevisions(t *testing.T) {
+	t.Parallel()
+	idxFile, key := tempStore(t)
+	p := pipeline.Edit(key, idxFile, func(path string) error {
+		return os.WriteFile(path, []byte("Tags: test, greeting\n---\nHello memo\nHow are you?\n"), 0o644)
+	})
+	if p.Error.Err != nil {
+		t.Fatalf("want no error from Edit, got %q", p.Error.Err)
+	}
+	g := pipeline.Diff([]string{key}, idxFile, false)
+	if g.Error.Err != nil {
+		t.Fatalf("want no error from Diff, got %q", g.Error.Err)
+	}
+	buf := &bytes.Buffer{}
+	g.Output = buf
+	g.Stdout()
+	lines := strings.Split(buf.String(), "\n")
+	if len(lines) < 2 {
+		t.Fatalf("want a diff, got %q", buf.String())
+	}
+	want := []string{
+		"+Tag: greeting",
+		"@@ -1 +1,2 @@",
+		"-Hello world",
+		"+Hello memo",
+		"+How are you?",
+		"",
+		"",
+	}
+	got := lines[2:]
+	if !cmp.Equal(want, got) {
+		t.Error(cmp.Diff(want, got))
+	}
+	if !strings.HasPrefix(lines[0], "--- 01G7GV@1 09.07.2022 22:26:15") {
+		t.Errorf("want the prior revision as old side, got %q", lines[0])
+	}
+}
+
+func TestDiffWithoutHistory(t *testing.T) {
+	t.Parallel()
+	idxFile, key := tempStore(t)
+	g := pipeline.Diff([]string{key}, idxFile, false)
+	if g.Error.Err == nil {
+		t.Fatal("want error for a memo without prior revision, got none")
+	}
+}
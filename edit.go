@@ -0,0 +1,228 @@
+package pipeline
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// Separates the header with the tags from the content while editing
+	editSeparator = "---"
+	editTags      = "Tags:"
+)
+
+//////////////////////////////////////////////////////////
+// Edit ... hands a stored memo over to an editor and
+// stores it again with the new content and tags, the
+// editor gets the path of a temporary file
+// used by edit
+//////////////////////////////////////////////////////////
+func Edit(ref, filePath string, editor func(path string) error) *Pipeline {
+	// Help wanted?
+	if ref == "help" {
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "EDIT is part of memo's famous toolbox")
+		fmt.Fprintln(os.Stderr, "-------------------------------------")
+		fmt.Fprintln(os.Stderr, "Usage: edit <ref>")
+		fmt.Fprintln(os.Stderr, "       edit help")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "It opens a stored memo in $EDITOR. The tags are in a header block,")
+		fmt.Fprintln(os.Stderr, "separated from the content by a line with three dashes only ...")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "  Tags: work, meeting")
+		fmt.Fprintln(os.Stderr, "  ---")
+		fmt.Fprintln(os.Stderr, "  Content of the memo")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "After saving, the memo gets a new hash and timestamp, the index is")
+		fmt.Fprintln(os.Stderr, "updated. The prior version is kept in the history of the memo.")
+		fmt.Fprintln(os.Stderr, "References are IDs, hashes or any unique prefix of them.")
+		fmt.Fprintln(os.Stderr)
+		toolbox()
+		os.Exit(0)
+	}
+	if filePath == "" {
+		filePath = TakeMeHome(basePath + string(os.PathSeparator) + indexFile)
+	}
+	idx := NewIndex()
+	err := idx.Load(filePath)
+	if err != nil {
+		return &Pipeline{
+			Error: MaskedError{
+				Prefix: "✘ error ... ",
+				Err:    err,
+			},
+		}
+	}
+	key, err := idx.Resolve(ref)
+	if err != nil {
+		return &Pipeline{
+			Error: MaskedError{
+				Prefix: "✘ error ... ",
+				Err:    err,
+			},
+		}
+	}
+	entry := idx.Find(key)
+	if entry.Deleted != "" {
+		return &Pipeline{
+			Error: MaskedError{
+				Prefix: "✘ error ... ",
+				Err:    fmt.Errorf("memo %s is in the trash, restore it first", key),
+			},
+		}
+	}
+	memo := Memo{}
+	err = memo.load(entry.Path, key)
+	if err != nil {
+		return &Pipeline{
+			Error: MaskedError{
+				Prefix: "✘ error ... ",
+				Err:    err,
+			},
+		}
+	}
+	// Hand the memo over to the editor
+	tmp, err := os.CreateTemp("", "memo-*.txt")
+	if err != nil {
+		return &Pipeline{
+			Error: MaskedError{
+				Prefix: "✘ error ... ",
+				Err:    err,
+			},
+		}
+	}
+	defer os.Remove(tmp.Name())
+	fmt.Fprintf(tmp, "%s %s\n%s\n%s", editTags, strings.Join(memo.Tags, ", "), editSeparator, memo.Content)
+	err = tmp.Close()
+	if err != nil {
+		return &Pipeline{
+			Error: MaskedError{
+				Prefix: "✘ error ... ",
+				Err:    err,
+			},
+		}
+	}
+	err = editor(tmp.Name())
+	if err != nil {
+		return &Pipeline{
+			Error: MaskedError{
+				Prefix: "✘ error ... ",
+				Err:    err,
+			},
+		}
+	}
+	edited, err := os.ReadFile(tmp.Name())
+	if err != nil {
+		return &Pipeline{
+			Error: MaskedError{
+				Prefix: "✘ error ... ",
+				Err:    err,
+			},
+		}
+	}
+	tags, content, err := parseEdited(string(edited))
+	if err != nil {
+		return &Pipeline{
+			Error: MaskedError{
+				Prefix: "✘ error ... ",
+				Err:    err,
+			},
+		}
+	}
+	if tags == nil {
+		tags = memo.Tags
+	}
+	if strings.TrimSpace(content) == "" {
+		return &Pipeline{
+			Error: MaskedError{
+				Prefix: "✘ error ... ",
+				Err:    errors.New("memo is empty, nothing stored"),
+			},
+		}
+	}
+	if content == memo.Content && strings.Join(tags, ",") == strings.Join(memo.Tags, ",") {
+		return &Pipeline{
+			Reader: strings.NewReader(key + " unchanged"),
+		}
+	}
+	// Store the changed memo at its new place
+	memo.Content = content
+	memo.Tags = tags
+	memo.Modified = time.Now().Format(timeLayout)
+	memo.Checksum, err = memo.Hash()
+	if err != nil {
+		return &Pipeline{
+			Error: MaskedError{
+				Prefix: "✘ error ... ",
+				Err:    err,
+			},
+		}
+	}
+	dir, err := memo.GetPath(filepath.Dir(filePath))
+	if err != nil {
+		return &Pipeline{
+			Error: MaskedError{
+				Prefix: "✘ error ... ",
+				Err:    err,
+			},
+		}
+	}
+	err = NewIndex().Update(filePath, func(i *Index) error {
+		_, err := archive(filepath.Dir(filePath), key, entry.Path)
+		if err != nil {
+			return err
+		}
+		err = memo.save(dir, key)
+		if err != nil {
+			return err
+		}
+		if dir != entry.Path {
+			err = os.Remove(entry.Path + string(os.PathSeparator) + key + ".memo")
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+		}
+		i.Upsert(key, memo.entry(dir))
+		return NewTermIndex().Update(termsPath(filePath), func(t *TermIndex) error {
+			t.Add(key, memo.Content)
+			return nil
+		})
+	})
+	if err != nil {
+		return &Pipeline{
+			Error: MaskedError{
+				Prefix: "✘ error ... ",
+				Err:    err,
+			},
+		}
+	}
+	return &Pipeline{
+		Reader: strings.NewReader(key + " " + memo.Modified),
+	}
+}
+
+// parseEdited ... splits an edited memo into tags and content, tags
+// are nil without header block
+func parseEdited(edited string) ([]string, string, error) {
+	scanner := bufio.NewScanner(strings.NewReader(edited))
+	if !scanner.Scan() || !strings.HasPrefix(scanner.Text(), editTags) {
+		return nil, edited, nil
+	}
+	header, rest, found := strings.Cut(edited, "\n"+editSeparator+"\n")
+	if !found {
+		return nil, "", fmt.Errorf("header block is missing the separator %q", editSeparator)
+	}
+	tags := []string{}
+	for _, tag := range strings.Split(strings.TrimPrefix(header, editTags), ",") {
+		tag = strings.TrimSpace(tag)
+		if tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags, rest, nil
+}
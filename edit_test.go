@@ -0,0 +1,93 @@
+package pipeline_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"pipeline"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestEdit(t *testing.T) {
+	t.Parallel()
+	idxFile, key := tempStore(t)
+	oldFile := filepath.Join(filepath.Dir(idxFile), key+".memo")
+	editor := func(path string) error {
+		got, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		want := "Tags: test\n---\nHello world\n"
+		if !cmp.Equal(want, string(got)) {
+			t.Error(cmp.Diff(want, string(got)))
+		}
+		return os.WriteFile(path, []byte("Tags: test, fixed\n---\nHello memo\n"), 0o644)
+	}
+	p := pipeline.Edit("30b2ef", idxFile, editor)
+	if p.Error.Err != nil {
+		t.Fatalf("want no error from Edit, got %q", p.Error.Err)
+	}
+	idx := pipeline.NewIndex()
+	err := idx.Load(idxFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := idx.Find(key)
+	if !entry.Tags["fixed"] {
+		t.Errorf("want new tag in the index, got %v", entry.Tags)
+	}
+	if entry.Checksum == "30b2efc5b47dca50dd651d291e52b237f8fe59b98f8996ac234a6ca2a0d4af80" {
+		t.Error("want a new checksum for the edited content")
+	}
+	_, err = os.Stat(oldFile)
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("want old memo file moved, got %v", err)
+	}
+	memo := pipeline.Memo{}
+	fi, err := os.Open(filepath.Join(entry.Path, key+".memo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fi.Close()
+	err = memo.Read(fi)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if memo.Content != "Hello memo\n" || memo.Checksum != entry.Checksum {
+		t.Errorf("want edited memo with matching checksum, got %+v", memo)
+	}
+}
+
+func TestEditWithoutHeaderKeepsTags(t *testing.T) {
+	t.Parallel()
+	idxFile, key := tempStore(t)
+	editor := func(path string) error {
+		return os.WriteFile(path, []byte("Just content\n"), 0o644)
+	}
+	p := pipeline.Edit(key, idxFile, editor)
+	if p.Error.Err != nil {
+		t.Fatalf("want no error from Edit, got %q", p.Error.Err)
+	}
+	idx := pipeline.NewIndex()
+	err := idx.Load(idxFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !idx.Find(key).Tags["test"] {
+		t.Errorf("want tags kept, got %v", idx.Find(key).Tags)
+	}
+}
+
+func TestEditEmpty(t *testing.T) {
+	t.Parallel()
+	idxFile, key := tempStore(t)
+	editor := func(path string) error {
+		return os.WriteFile(path, []byte("Tags: test\n---\n\n"), 0o644)
+	}
+	p := pipeline.Edit(key, idxFile, editor)
+	if p.Error.Err == nil {
+		t.Fatal("want error for an empty memo, got none")
+	}
+}
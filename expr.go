@@ -0,0 +1,239 @@
+package pipeline
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type (
+	// TagExpr ... a boolean expression over tags like
+	// work and (urgent or blocked) and not done
+	TagExpr interface {
+		Match(tags map[string]bool, exact bool) bool
+	}
+
+	// ExprError ... a syntax error at a position of an expression,
+	// positions count runes starting with 1
+	ExprError struct {
+		Expr string
+		Pos  int
+		Msg  string
+	}
+
+	tagTerm string
+	notExpr struct{ expr TagExpr }
+	andExpr []TagExpr
+	orExpr  []TagExpr
+
+	exprToken struct {
+		text string
+		pos  int
+	}
+
+	exprParser struct {
+		expr   string
+		tokens []exprToken
+		next   int
+		cfg    Config
+	}
+)
+
+//////////////////////////////////////////////////////////
+// ParseTagExpr ... parses an expression of tags combined
+// by and, or, not and parentheses, tags next to each other
+// are combined by and, not binds before and before or
+//////////////////////////////////////////////////////////
+func ParseTagExpr(expr string, cfg Config) (TagExpr, error) {
+	p := &exprParser{expr: expr, tokens: tokenize(expr), cfg: cfg}
+	if len(p.tokens) == 0 {
+		return nil, p.errorf(1, "expression is empty")
+	}
+	tree, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.next < len(p.tokens) {
+		return nil, p.errorf(p.tokens[p.next].pos, "unexpected %q", p.tokens[p.next].text)
+	}
+	return tree, nil
+}
+
+// parseTagArgs ... parses the arguments of tagged one by one, only an
+// argument holding more than one word or parenthesis is an expression,
+// others are plain tags, even if named and, or or not, all arguments
+// must match, no arguments at all match everything
+func parseTagArgs(args []string, cfg Config) (TagExpr, error) {
+	exprs := andExpr{}
+	for _, arg := range args {
+		if len(tokenize(arg)) > 1 {
+			expr, err := ParseTagExpr(arg, cfg)
+			if err != nil {
+				return nil, err
+			}
+			exprs = append(exprs, expr)
+			continue
+		}
+		tag, err := NormalizeTag(arg, cfg)
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, tagTerm(tag))
+	}
+	return exprs, nil
+}
+
+// Match ... tells if the tag is among tags
+func (t tagTerm) Match(tags map[string]bool, exact bool) bool {
+	return hasTag(tags, string(t), exact)
+}
+
+// Match ... negates the inner expression
+func (n notExpr) Match(tags map[string]bool, exact bool) bool {
+	return !n.expr.Match(tags, exact)
+}
+
+// Match ... tells if all expressions match
+func (a andExpr) Match(tags map[string]bool, exact bool) bool {
+	for _, expr := range a {
+		if !expr.Match(tags, exact) {
+			return false
+		}
+	}
+	return true
+}
+
+// Match ... tells if any expression matches
+func (o orExpr) Match(tags map[string]bool, exact bool) bool {
+	for _, expr := range o {
+		if expr.Match(tags, exact) {
+			return true
+		}
+	}
+	return false
+}
+
+// or ... and { "or" and }
+func (p *exprParser) or() (TagExpr, error) {
+	first, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	exprs := orExpr{first}
+	for p.keyword("or") {
+		p.next++
+		expr, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, expr)
+	}
+	if len(exprs) == 1 {
+		return first, nil
+	}
+	return exprs, nil
+}
+
+// and ... not { ["and"] not }
+func (p *exprParser) and() (TagExpr, error) {
+	first, err := p.not()
+	if err != nil {
+		return nil, err
+	}
+	exprs := andExpr{first}
+	for p.next < len(p.tokens) && !p.keyword("or") && p.tokens[p.next].text != ")" {
+		if p.keyword("and") {
+			p.next++
+		}
+		expr, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, expr)
+	}
+	if len(exprs) == 1 {
+		return first, nil
+	}
+	return exprs, nil
+}
+
+// not ... "not" not | "(" or ")" | tag
+func (p *exprParser) not() (TagExpr, error) {
+	if p.next == len(p.tokens) {
+		return nil, p.errorf(utf8.RuneCountInString(p.expr)+1, "tag expected at the end")
+	}
+	token := p.tokens[p.next]
+	switch {
+	case p.keyword("not"):
+		p.next++
+		expr, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		return notExpr{expr}, nil
+	case token.text == "(":
+		p.next++
+		expr, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if p.next == len(p.tokens) || p.tokens[p.next].text != ")" {
+			return nil, p.errorf(token.pos, "missing ) for this (")
+		}
+		p.next++
+		return expr, nil
+	case token.text == ")" || p.keyword("and") || p.keyword("or"):
+		return nil, p.errorf(token.pos, "tag expected instead of %q", token.text)
+	}
+	p.next++
+	tag, err := NormalizeTag(token.text, p.cfg)
+	if err != nil {
+		return nil, p.errorf(token.pos, "%s", err)
+	}
+	return tagTerm(tag), nil
+}
+
+// keyword ... tells if the next token is the keyword, case doesn't matter
+func (p *exprParser) keyword(word string) bool {
+	return p.next < len(p.tokens) && strings.EqualFold(p.tokens[p.next].text, word)
+}
+
+func (p *exprParser) errorf(pos int, format string, args ...interface{}) error {
+	return &ExprError{Expr: p.expr, Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+// tokenize ... splits an expression into parentheses and words
+func tokenize(expr string) []exprToken {
+	tokens := []exprToken{}
+	pos := 0
+	word := ""
+	start := 0
+	flush := func() {
+		if word != "" {
+			tokens = append(tokens, exprToken{word, start})
+			word = ""
+		}
+	}
+	for _, r := range expr {
+		pos++
+		switch {
+		case unicode.IsSpace(r):
+			flush()
+		case r == '(' || r == ')':
+			flush()
+			tokens = append(tokens, exprToken{string(r), pos})
+		default:
+			if word == "" {
+				start = pos
+			}
+			word += string(r)
+		}
+	}
+	flush()
+	return tokens
+}
+
+func (e *ExprError) Error() string {
+	return fmt.Sprintf("%s at position %d\n  %s\n  %s^", e.Msg, e.Pos, e.Expr, strings.Repeat(" ", e.Pos-1))
+}
@@ -0,0 +1,57 @@
+package pipeline_test
+
+import (
+	"errors"
+	"pipeline"
+	"testing"
+)
+
+func TestTagExprMatch(t *testing.T) {
+	t.Parallel()
+	tags := map[string]bool{"work": true, "blocked": true, "project/alpha/design": true}
+	tests := map[string]bool{
+		"work":        true,
+		"work urgent": false,
+		"work and (urgent or blocked) and not done":   true,
+		"work and (urgent or blocked) and not design": true,
+		"work AND NOT blocked":                        false,
+		"urgent or work and blocked":                  true,
+		"(urgent or work) and not (blocked or done)":  false,
+		"not not work":                                true,
+		"project/alpha and not project/beta":          true,
+	}
+	for expr, want := range tests {
+		tree, err := pipeline.ParseTagExpr(expr, pipeline.Config{})
+		if err != nil {
+			t.Errorf("want no error for %q, got %q", expr, err)
+			continue
+		}
+		if got := tree.Match(tags, false); want != got {
+			t.Errorf("want %v for %q, got %v", want, expr, got)
+		}
+	}
+}
+
+func TestTagExprErrors(t *testing.T) {
+	t.Parallel()
+	tests := map[string]int{
+		"":                  1,
+		"work and":          9,
+		"(work or urgent":   1,
+		"work) or urgent":   5,
+		"work and or done":  10,
+		"work and not":      13,
+		"work and semi;col": 10,
+	}
+	for expr, want := range tests {
+		_, err := pipeline.ParseTagExpr(expr, pipeline.Config{})
+		exprErr := &pipeline.ExprError{}
+		if !errors.As(err, &exprErr) {
+			t.Errorf("want an expression error for %q, got %v", expr, err)
+			continue
+		}
+		if exprErr.Pos != want {
+			t.Errorf("want error at position %d for %q, got %d", want, expr, exprErr.Pos)
+		}
+	}
+}
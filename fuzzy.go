@@ -0,0 +1,260 @@
+package pipeline
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+//////////////////////////////////////////////////////////
+// Fuzzy ... reduces an index to entries, whose memos
+// contain words close to the ones searched for, within
+// the content or the tags, the closest come first
+// used by fuzzy
+//////////////////////////////////////////////////////////
+func Fuzzy(rd io.Reader, filePath string, tags bool, words ...string) *Pipeline {
+	// Help wanted?
+	if len(words) > 0 && words[0] == "help" {
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "FUZZY is part of memo's famous toolbox")
+		fmt.Fprintln(os.Stderr, "--------------------------------------")
+		fmt.Fprintln(os.Stderr, "Usage: further tools | fuzzy [--tags] <word> [<word2> <wordn>] | further tools")
+		fmt.Fprintln(os.Stderr, "       fuzzy help")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "It works like search, but forgives typos. A word matches words of the")
+		fmt.Fprintln(os.Stderr, "memos, which share some trigrams and are a few edits away, swapped")
+		fmt.Fprintln(os.Stderr, "letters count as one edit. So kuberentes finds kubernetes. Only these")
+		fmt.Fprintln(os.Stderr, "entries, which come close to all words, survive and get piped to the")
+		fmt.Fprintln(os.Stderr, "next tool in the chain, the closest first.")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "With --tags the words get compared to the tags and their levels.")
+		fmt.Fprintln(os.Stderr)
+		toolbox()
+		os.Exit(0)
+	}
+	query := []string{}
+	for _, word := range words {
+		query = append(query, terms(word)...)
+	}
+	if len(query) == 0 {
+		return &Pipeline{
+			Error: MaskedError{
+				Prefix: "✘ error ... ",
+				Err:    fmt.Errorf("nothing to search for"),
+			},
+		}
+	}
+	entries, err := ReadStream(rd)
+	if err != nil {
+		return &Pipeline{
+			Error: MaskedError{
+				Prefix: "✘ error ... ",
+				Err:    err,
+			},
+		}
+	}
+	if filePath == "" {
+		filePath = TakeMeHome(basePath + string(os.PathSeparator) + indexFile)
+	}
+	t := NewTermIndex()
+	if !tags {
+		err = t.Load(termsPath(filePath))
+		if err != nil {
+			return &Pipeline{
+				Error: MaskedError{
+					Prefix: "✘ error ... ",
+					Err:    err,
+				},
+			}
+		}
+		// Memos missing in the terms file count for this search only
+		for _, entry := range entries {
+			if t.Has(entry.Key) {
+				continue
+			}
+			memo := Memo{}
+			err := memo.load(entry.Path, entry.Key)
+			if err != nil {
+				return &Pipeline{
+					Error: MaskedError{
+						Prefix: "✘ error ... ",
+						Err:    err,
+					},
+				}
+			}
+			t.Add(entry.Key, memo.Content)
+		}
+	}
+	candidates := make([]map[string]float64, len(query))
+	for n, word := range query {
+		candidates[n] = t.FuzzyTerms(word)
+	}
+
+	found := Stream{}
+	for _, entry := range entries {
+		score := 0.0
+		for n, word := range query {
+			best := 0.0
+			if tags {
+				for _, tag := range entry.SortedTags() {
+					for _, level := range append([]string{tag}, strings.Split(tag, tagLevel)...) {
+						if s, ok := FuzzyScore(word, strings.ToLower(level)); ok && s > best {
+							best = s
+						}
+					}
+				}
+			} else {
+				for term, s := range candidates[n] {
+					if _, exist := t.Terms[term][entry.Key]; exist && s > best {
+						best = s
+					}
+				}
+			}
+			if best == 0 {
+				score = 0
+				break
+			}
+			score += best
+		}
+		if score > 0 {
+			entry.Score = score
+			found = append(found, entry)
+		}
+	}
+	found.Rank()
+
+	filtered, err := Marshal(found)
+	if err != nil {
+		return &Pipeline{
+			Error: MaskedError{
+				Prefix: "✘ error ... ",
+				Err:    err,
+			},
+		}
+	}
+
+	return &Pipeline{
+		Reader: filtered,
+	}
+}
+
+// FuzzyTerms ... the indexed terms close to word with their score
+func (t *TermIndex) FuzzyTerms(word string) map[string]float64 {
+	near := make(map[string]float64)
+	grams := trigrams(word)
+	for term := range t.Terms {
+		if !sharesAny(grams, trigrams(term)) {
+			continue
+		}
+		if score, ok := FuzzyScore(word, term); ok {
+			near[term] = score
+		}
+	}
+	return near
+}
+
+// FuzzyScore ... tells whether word is close enough to the searched
+// one and how close between 0 and 1, both edit distance and shared
+// trigrams count
+func FuzzyScore(searched, word string) (float64, bool) {
+	if searched == word {
+		return 1, true
+	}
+	distance := EditDistance(searched, word)
+	if distance > maxEdits(utf8.RuneCountInString(searched)) {
+		return 0, false
+	}
+	longest := utf8.RuneCountInString(searched)
+	if n := utf8.RuneCountInString(word); n > longest {
+		longest = n
+	}
+	edits := 1 - float64(distance)/float64(longest)
+	return (edits + trigramSimilarity(searched, word)) / 2, true
+}
+
+// EditDistance ... the number of inserted, deleted, substituted or
+// swapped runes needed to turn a into b
+func EditDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	// Three rows are enough for swaps of neighbours
+	before, previous, current := make([]int, len(rb)+1), make([]int, len(rb)+1), make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = previous[j-1] + cost
+			if d := previous[j] + 1; d < current[j] {
+				current[j] = d
+			}
+			if d := current[j-1] + 1; d < current[j] {
+				current[j] = d
+			}
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				if d := before[j-2] + 1; d < current[j] {
+					current[j] = d
+				}
+			}
+		}
+		before, previous, current = previous, current, before
+	}
+	return previous[len(rb)]
+}
+
+// maxEdits ... the edits forgiven for a word of length n
+func maxEdits(n int) int {
+	switch {
+	case n < 3:
+		return 0
+	case n <= 5:
+		return 1
+	case n <= 9:
+		return 2
+	}
+	return 3
+}
+
+// trigrams ... the runes of a word in threes, padded by blanks so
+// the start and the end count more
+func trigrams(word string) map[string]bool {
+	runes := []rune("  " + word + " ")
+	grams := make(map[string]bool)
+	for n := 0; n+3 <= len(runes); n++ {
+		grams[string(runes[n:n+3])] = true
+	}
+	return grams
+}
+
+// trigramSimilarity ... the shared trigrams of two words in relation
+// to all of them
+func trigramSimilarity(a, b string) float64 {
+	ga, gb := trigrams(a), trigrams(b)
+	shared := 0
+	for gram := range ga {
+		if gb[gram] {
+			shared++
+		}
+	}
+	all := len(ga) + len(gb) - shared
+	if all == 0 {
+		return 0
+	}
+	return float64(shared) / float64(all)
+}
+
+// sharesAny ... tells whether two sets of trigrams overlap
+func sharesAny(a, b map[string]bool) bool {
+	for gram := range a {
+		if b[gram] {
+			return true
+		}
+	}
+	return false
+}
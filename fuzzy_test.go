@@ -0,0 +1,87 @@
+package pipeline_test
+
+import (
+	"pipeline"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestEditDistance(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"kubernetes", "kubernetes", 0},
+		{"kuberentes", "kubernetes", 1},
+		{"kitten", "sitting", 3},
+		{"", "abc", 3},
+		{"café", "cafe", 1},
+	}
+	for _, test := range tests {
+		got := pipeline.EditDistance(test.a, test.b)
+		if got != test.want {
+			t.Errorf("%q to %q: want %d, got %d", test.a, test.b, test.want, got)
+		}
+	}
+}
+
+func TestFuzzyScore(t *testing.T) {
+	t.Parallel()
+	exact, ok := pipeline.FuzzyScore("deploy", "deploy")
+	if !ok || exact != 1 {
+		t.Errorf("want 1 for equal words, got %f", exact)
+	}
+	typo, ok := pipeline.FuzzyScore("deplyo", "deploy")
+	if !ok || typo >= exact || typo <= 0 {
+		t.Errorf("want a score between 0 and 1 for a typo, got %f", typo)
+	}
+	_, ok = pipeline.FuzzyScore("deploy", "destroy")
+	if ok {
+		t.Error("want no match for too many edits")
+	}
+	_, ok = pipeline.FuzzyScore("go", "no")
+	if ok {
+		t.Error("want short words to match exactly only")
+	}
+}
+
+func TestFuzzy(t *testing.T) {
+	t.Parallel()
+	idxFile, key := tempStore(t)
+	addMemo(t, idxFile, "01G7H0000000000000000000F1", []string{"ops/kubernetes"}, "Upgrade the kubernetes cluster\n")
+	addMemo(t, idxFile, "01G7H0000000000000000000F2", []string{"ops"}, "Kubernets upgrade, another typo\n")
+	tests := []struct {
+		tags  bool
+		words []string
+		want  []string
+	}{
+		{false, []string{"kuberentes"}, []string{"01G7H0000000000000000000F1", "01G7H0000000000000000000F2"}},
+		{false, []string{"wrold", "helo"}, []string{key}},
+		{false, []string{"kuberentes", "another"}, []string{"01G7H0000000000000000000F2"}},
+		{true, []string{"kuberntes"}, []string{"01G7H0000000000000000000F1"}},
+		{true, []string{"tset"}, []string{key}},
+	}
+	for _, test := range tests {
+		f := pipeline.From(pipeline.PeriodAll, idxFile)
+		if f.Error.Err != nil {
+			t.Fatalf("want no error from From, got %q", f.Error.Err)
+		}
+		p := pipeline.Fuzzy(f.Reader, idxFile, test.tags, test.words...)
+		if p.Error.Err != nil {
+			t.Fatalf("want no error from Fuzzy, got %q", p.Error.Err)
+		}
+		found, err := pipeline.ReadStream(p.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := []string{}
+		for _, entry := range found {
+			got = append(got, entry.Key)
+		}
+		if !cmp.Equal(test.want, got) {
+			t.Errorf("%q: %s", test.words, cmp.Diff(test.want, got))
+		}
+	}
+}
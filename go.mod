@@ -2,4 +2,7 @@ module pipeline
 
 go 1.18
 
-require github.com/google/go-cmp v0.5.8
+require (
+	github.com/google/go-cmp v0.5.8
+	golang.org/x/text v0.14.0
+)
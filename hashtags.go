@@ -0,0 +1,156 @@
+package pipeline
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+type (
+	// HashtagConfig ... how hashtags in the content become tags
+	HashtagConfig struct {
+		Off        bool // no hashtags get extracted at all
+		Strip      bool // the # markers get removed from the content
+		CodeBlocks bool // hashtags within code count as well
+	}
+
+	// hashtagger ... finds hashtags line by line and remembers,
+	// if the lines are within a fenced code block
+	hashtagger struct {
+		cfg   HashtagConfig
+		fence string
+		tags  []string
+	}
+)
+
+// A hashtag starts at the beginning of a line or after a character,
+// which is no part of words, URLs or HTML entities
+var hashtag = regexp.MustCompile(`(^|[^\p{L}\p{N}_&/#])#([\p{L}\p{N}_][\p{L}\p{N}_\-/]*)`)
+
+//////////////////////////////////////////////////////////
+// Hashtags ... turns the hashtags of a memo's content into
+// tags, for memos converted before or without hashtags
+// used by hashtags
+//////////////////////////////////////////////////////////
+func Hashtags(rd io.Reader, what, filePath string) *Pipeline {
+	// Help wanted?
+	if what == "help" {
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "HASHTAGS is part of memo's famous toolbox")
+		fmt.Fprintln(os.Stderr, "-----------------------------------------")
+		fmt.Fprintln(os.Stderr, "Usage: memo | hashtags | further tools")
+		fmt.Fprintln(os.Stderr, "       hashtags help")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "It takes a memo from the pipe and adds hashtags like #deploy within")
+		fmt.Fprintln(os.Stderr, "its content as tags. memo does the same right away. The config of")
+		fmt.Fprintln(os.Stderr, "the store decides about the details ...")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "  \"Hashtags\": {")
+		fmt.Fprintln(os.Stderr, "    \"Off\": false,        no hashtags get extracted by memo")
+		fmt.Fprintln(os.Stderr, "    \"Strip\": false,      the # markers get removed from the content")
+		fmt.Fprintln(os.Stderr, "    \"CodeBlocks\": false  hashtags within code count as well")
+		fmt.Fprintln(os.Stderr, "  }")
+		fmt.Fprintln(os.Stderr)
+		toolbox()
+		os.Exit(0)
+	}
+	memo := Memo{}
+	err := Unmarshal(rd, &memo)
+	if err != nil {
+		return &Pipeline{
+			Error: MaskedError{
+				Prefix: "✘ error ... ",
+				Err:    err,
+			},
+		}
+	}
+	cfg, err := LoadConfig(filePath)
+	if err != nil {
+		return &Pipeline{
+			Error: MaskedError{
+				Prefix: "✘ error ... ",
+				Err:    err,
+			},
+		}
+	}
+	// The stage works, even if memo doesn't extract them
+	h := &hashtagger{cfg: cfg.Hashtags}
+	lines := strings.SplitAfter(memo.Content, "\n")
+	for n, line := range lines {
+		lines[n] = h.scan(line)
+	}
+	memo.Content = strings.Join(lines, "")
+	memo.Tags, err = NormalizeTags(append(memo.Tags, h.tags...), cfg)
+	if err != nil {
+		return &Pipeline{
+			Error: MaskedError{
+				Prefix: "✘ error ... ",
+				Err:    err,
+			},
+		}
+	}
+	r, err := Marshal(memo)
+	if err != nil {
+		return &Pipeline{
+			Error: MaskedError{
+				Prefix: "✘ error ... ",
+				Err:    err,
+			},
+		}
+	}
+	return &Pipeline{
+		Reader: r,
+	}
+}
+
+// scan ... collects the hashtags of a line and returns the line,
+// without markers if they get stripped
+func (h *hashtagger) scan(line string) string {
+	trimmed := strings.TrimSpace(line)
+	if h.fence != "" {
+		if strings.HasPrefix(trimmed, h.fence) {
+			h.fence = ""
+		}
+		if !h.cfg.CodeBlocks {
+			return line
+		}
+	} else if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+		h.fence = trimmed[:3]
+		if !h.cfg.CodeBlocks {
+			return line
+		}
+	}
+	// Inline code is every second part between backticks
+	parts := strings.Split(line, "`")
+	for n, part := range parts {
+		if n%2 == 1 && n < len(parts)-1 && !h.cfg.CodeBlocks {
+			continue
+		}
+		parts[n] = hashtag.ReplaceAllStringFunc(part, func(found string) string {
+			match := hashtag.FindStringSubmatch(found)
+			tag := strings.TrimRight(match[2], "-/")
+			if !isHashtag(tag) {
+				return found
+			}
+			h.tags = append(h.tags, tag)
+			if h.cfg.Strip {
+				return match[1] + match[2]
+			}
+			return found
+		})
+	}
+	return strings.Join(parts, "`")
+}
+
+// isHashtag ... tells if a candidate makes a valid tag, numbers like
+// in fix #12 refer to issues and broken ones like #foo//bar stay text
+func isHashtag(tag string) bool {
+	if strings.IndexFunc(tag, func(r rune) bool { return !unicode.IsDigit(r) }) < 0 {
+		return false
+	}
+	_, err := NormalizeTag(tag, Config{})
+	return err == nil
+}
@@ -0,0 +1,63 @@
+package pipeline_test
+
+import (
+	"pipeline"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const hashtagged = "Rollout of #deploy/v2, see issue#12 and C# docs\n" +
+	"# Heading, no tag\n" +
+	"Still #Incident at https://example.com/#anchor\n" +
+	"Broken #foo//bar and fix #12 stay text\n" +
+	"Inline `#notag` code\n" +
+	"```\n" +
+	"#include <stdio.h>\n" +
+	"```\n"
+
+func TestToJSONHashtags(t *testing.T) {
+	t.Parallel()
+	p := pipeline.ToJSON(strings.NewReader(hashtagged), "testdata/index.dat")
+	if p.Error.Err != nil {
+		t.Fatalf("want no error from ToJSON, got %q", p.Error.Err)
+	}
+	memo := pipeline.Memo{}
+	err := pipeline.Unmarshal(p.Reader, &memo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"deploy/v2", "incident"}
+	if !cmp.Equal(want, memo.Tags) {
+		t.Error(cmp.Diff(want, memo.Tags))
+	}
+	if memo.Content != hashtagged {
+		t.Error(cmp.Diff(hashtagged, memo.Content))
+	}
+}
+
+func TestHashtags(t *testing.T) {
+	t.Parallel()
+	r, err := pipeline.Marshal(pipeline.Memo{
+		Tags:     []string{"work"},
+		Modified: "09.07.2022 22:26:15",
+		Content:  hashtagged,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := pipeline.Hashtags(r, "", "testdata/index.dat")
+	if p.Error.Err != nil {
+		t.Fatalf("want no error from Hashtags, got %q", p.Error.Err)
+	}
+	memo := pipeline.Memo{}
+	err = pipeline.Unmarshal(p.Reader, &memo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"deploy/v2", "incident", "work"}
+	if !cmp.Equal(want, memo.Tags) {
+		t.Error(cmp.Diff(want, memo.Tags))
+	}
+}
@@ -0,0 +1,167 @@
+package pipeline
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const historyDir = "history"
+
+//////////////////////////////////////////////////////////
+// History ... lists all revisions of a memo, the oldest
+// first and the current one last
+// used by history
+//////////////////////////////////////////////////////////
+func History(ref, filePath string) *Pipeline {
+	// Help wanted?
+	if ref == "help" {
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "HISTORY is part of memo's famous toolbox")
+		fmt.Fprintln(os.Stderr, "----------------------------------------")
+		fmt.Fprintln(os.Stderr, "Usage: history <ref>")
+		fmt.Fprintln(os.Stderr, "       history help")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "It lists all revisions of a memo with the timestamps of their changes.")
+		fmt.Fprintln(os.Stderr, "Each revision is referenced by <ref>@<n>, so show <ref>@2 prints the")
+		fmt.Fprintln(os.Stderr, "second one. The last revision is the current memo.")
+		fmt.Fprintln(os.Stderr)
+		toolbox()
+		os.Exit(0)
+	}
+	if filePath == "" {
+		filePath = TakeMeHome(basePath + string(os.PathSeparator) + indexFile)
+	}
+	idx := NewIndex()
+	err := idx.Load(filePath)
+	if err != nil {
+		return &Pipeline{
+			Error: MaskedError{
+				Prefix: "✘ error ... ",
+				Err:    err,
+			},
+		}
+	}
+	key, err := idx.Resolve(ref)
+	if err != nil {
+		return &Pipeline{
+			Error: MaskedError{
+				Prefix: "✘ error ... ",
+				Err:    err,
+			},
+		}
+	}
+	revs, err := revisions(filepath.Dir(filePath), key)
+	if err != nil {
+		return &Pipeline{
+			Error: MaskedError{
+				Prefix: "✘ error ... ",
+				Err:    err,
+			},
+		}
+	}
+	current := Memo{}
+	err = current.load(idx.Find(key).Path, key)
+	if err != nil {
+		return &Pipeline{
+			Error: MaskedError{
+				Prefix: "✘ error ... ",
+				Err:    err,
+			},
+		}
+	}
+	revs = append(revs, current)
+	prefix := idx.Prefixes()[key]
+	buf := &bytes.Buffer{}
+	for n, memo := range revs {
+		checksum := memo.Checksum
+		if len(checksum) > minPrefix {
+			checksum = checksum[:minPrefix]
+		}
+		fmt.Fprintf(buf, "%s@%d %s %s %s", prefix, n+1, memo.Modified, checksum, strings.Join(memo.Tags, ", "))
+		if n == len(revs)-1 {
+			fmt.Fprint(buf, " (current)")
+		}
+		fmt.Fprintln(buf)
+	}
+	return &Pipeline{
+		Reader: buf,
+	}
+}
+
+//////////////////////////////////////////////////////////
+// SplitRevision ... separates a reference like abc@2 into
+// the memo's reference and the revision, which is 0 for
+// the current memo
+//////////////////////////////////////////////////////////
+func SplitRevision(ref string) (string, int, error) {
+	ref, rev, found := strings.Cut(ref, "@")
+	if !found {
+		return ref, 0, nil
+	}
+	n, err := strconv.Atoi(rev)
+	if err != nil || n < 1 {
+		return "", 0, fmt.Errorf("invalid revision %q", rev)
+	}
+	return ref, n, nil
+}
+
+// archive ... copies the memo with key from dir into its history as
+// the next revision, which number is returned, a memo without file
+// has no history to keep
+func archive(base, key, dir string) (int, error) {
+	memo := Memo{}
+	err := memo.load(dir, key)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	revs, err := revisions(base, key)
+	if err != nil {
+		return 0, err
+	}
+	rev := len(revs) + 1
+	return rev, memo.save(filepath.Join(base, historyDir, key), strconv.Itoa(rev))
+}
+
+// revisions ... reads all prior versions of a memo, the oldest first
+func revisions(base, key string) ([]Memo, error) {
+	dir := filepath.Join(base, historyDir, key)
+	revs := []Memo{}
+	for n := 1; ; n++ {
+		memo := Memo{}
+		err := memo.load(dir, strconv.Itoa(n))
+		if errors.Is(err, os.ErrNotExist) {
+			return revs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		revs = append(revs, memo)
+	}
+}
+
+// revision ... reads a memo in the given revision, 0 is the current one
+func revision(base, key string, entry IndexEntry, rev int) (Memo, error) {
+	memo := Memo{}
+	if rev == 0 {
+		return memo, memo.load(entry.Path, key)
+	}
+	revs, err := revisions(base, key)
+	if err != nil {
+		return memo, err
+	}
+	switch {
+	case rev <= len(revs):
+		return revs[rev-1], nil
+	case rev == len(revs)+1:
+		return memo, memo.load(entry.Path, key)
+	}
+	return memo, fmt.Errorf("memo %s has no revision %d, only %d", key, rev, len(revs)+1)
+}
@@ -0,0 +1,99 @@
+package pipeline_test
+
+import (
+	"bytes"
+	"os"
+	"pipeline"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestHistory(t *testing.T) {
+	t.Parallel()
+	idxFile, key := tempStore(t)
+	for _, content := range []string{"Hello memo\n", "Hello again\n"} {
+		content := content
+		p := pipeline.Edit(key, idxFile, func(path string) error {
+			return os.WriteFile(path, []byte("Tags: test\n---\n"+content), 0o644)
+		})
+		if p.Error.Err != nil {
+			t.Fatalf("want no error from Edit, got %q", p.Error.Err)
+		}
+	}
+	p := pipeline.History(key, idxFile)
+	if p.Error.Err != nil {
+		t.Fatalf("want no error from History, got %q", p.Error.Err)
+	}
+	buf := &bytes.Buffer{}
+	p.Output = buf
+	p.Stdout()
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("want 3 revisions, got %q", lines)
+	}
+	if !strings.HasPrefix(lines[0], "01G7GV@1 09.07.2022 22:26:15 30b2ef") {
+		t.Errorf("want the original memo first, got %q", lines[0])
+	}
+	if !strings.HasSuffix(lines[2], "(current)") {
+		t.Errorf("want the current memo last, got %q", lines[2])
+	}
+	tests := map[string]string{
+		key + "@1": "\nHello world\n\n",
+		key + "@2": "\nHello memo\n\n",
+		key + "@3": "\nHello again\n\n",
+		key:        "\nHello again\n\n",
+	}
+	for ref, want := range tests {
+		g := pipeline.Show(ref, "short", idxFile)
+		if g.Error.Err != nil {
+			t.Errorf("want no error from Show for %q, got %q", ref, g.Error.Err)
+			continue
+		}
+		buf := &bytes.Buffer{}
+		g.Output = buf
+		g.Stdout()
+		if !cmp.Equal(want, buf.String()) {
+			t.Error(cmp.Diff(want, buf.String()))
+		}
+	}
+	g := pipeline.Show(key+"@4", "short", idxFile)
+	if g.Error.Err == nil {
+		t.Error("want error for a missing revision, got none")
+	}
+}
+
+func TestHistoryOfTagChanges(t *testing.T) {
+	t.Parallel()
+	idxFile, key := tempStore(t)
+	f := pipeline.From(pipeline.PeriodAll, idxFile)
+	if f.Error.Err != nil {
+		t.Fatalf("want no error from From, got %q", f.Error.Err)
+	}
+	p := pipeline.Retag(f.Reader, []string{"+done"}, idxFile)
+	if p.Error.Err != nil {
+		t.Fatalf("want no error from Retag, got %q", p.Error.Err)
+	}
+	_, err := pipeline.RenameTag("done", "finished", idxFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := pipeline.History(key, idxFile)
+	if h.Error.Err != nil {
+		t.Fatalf("want no error from History, got %q", h.Error.Err)
+	}
+	buf := &bytes.Buffer{}
+	h.Output = buf
+	h.Stdout()
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("want 3 revisions, got %q", lines)
+	}
+	want := []string{"test", "done, test", "finished, test (current)"}
+	for n, line := range lines {
+		if !strings.HasSuffix(line, want[n]) {
+			t.Errorf("want revision %d tagged %q, got %q", n+1, want[n], line)
+		}
+	}
+}
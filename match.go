@@ -0,0 +1,148 @@
+package pipeline
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// MatchOptions ... what match looks at and what it pipes
+type MatchOptions struct {
+	// Field is content (default), tags or modified
+	Field string
+	// Invert keeps the entries, which don't match
+	Invert bool
+	// OnlyMatching prints the matching lines instead of piping entries
+	OnlyMatching bool
+}
+
+//////////////////////////////////////////////////////////
+// Match ... reduces an index to entries, whose memos
+// match a regular expression
+// used by match
+//////////////////////////////////////////////////////////
+func Match(rd io.Reader, pattern string, opts MatchOptions, filePath string) *Pipeline {
+	// Help wanted?
+	if pattern == "help" {
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "MATCH is part of memo's famous toolbox")
+		fmt.Fprintln(os.Stderr, "--------------------------------------")
+		fmt.Fprintln(os.Stderr, "Usage: further tools | match [<options>] <regexp> | further tools")
+		fmt.Fprintln(os.Stderr, "       further tools | match --only-matching [<options>] <regexp>")
+		fmt.Fprintln(os.Stderr, "       match help")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "It takes a list of index entries for memos from the pipe. Only these")
+		fmt.Fprintln(os.Stderr, "entries, where a line of the memo matches the regular expression, will")
+		fmt.Fprintln(os.Stderr, "survive and get piped to the next tool in the chain. The syntax is")
+		fmt.Fprintln(os.Stderr, "the one of Go like (?i)error|panic for ignoring the case.")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Following options are supported by now ...")
+		fmt.Fprintln(os.Stderr, "  ✓ --tags ... match the tags instead of the content")
+		fmt.Fprintln(os.Stderr, "  ✓ --modified ... match the modification time instead")
+		fmt.Fprintln(os.Stderr, "  ✓ -v, --invert ... keep the entries, which don't match")
+		fmt.Fprintln(os.Stderr, "  ✓ -o, --only-matching ... print the matching lines with memo refs")
+		fmt.Fprintln(os.Stderr)
+		toolbox()
+		os.Exit(0)
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return &Pipeline{
+			Error: MaskedError{
+				Prefix: "✘ error ... ",
+				Err:    err,
+			},
+		}
+	}
+	if opts.Field == "" {
+		opts.Field = "content"
+	}
+	if opts.Field != "content" && opts.Field != "tags" && opts.Field != "modified" {
+		return &Pipeline{
+			Error: MaskedError{
+				Prefix: "✘ error ... ",
+				Err:    fmt.Errorf("unknown field %q", opts.Field),
+			},
+		}
+	}
+	entries, err := ReadStream(rd)
+	if err != nil {
+		return &Pipeline{
+			Error: MaskedError{
+				Prefix: "✘ error ... ",
+				Err:    err,
+			},
+		}
+	}
+
+	refs := RefIndex(entries.Entries(), filePath).Prefixes()
+	found := Stream{}
+	buf := &bytes.Buffer{}
+	for _, entry := range entries {
+		var lines []string
+		switch opts.Field {
+		case "tags":
+			lines = entry.SortedTags()
+		case "modified":
+			lines = []string{entry.Modified}
+		default:
+			memo := Memo{}
+			err := memo.load(entry.Path, entry.Key)
+			if err != nil {
+				return &Pipeline{
+					Error: MaskedError{
+						Prefix: "✘ error ... ",
+						Err:    err,
+					},
+				}
+			}
+			lines = strings.Split(strings.TrimSuffix(memo.Content, "\n"), "\n")
+		}
+		if opts.OnlyMatching {
+			for n, line := range lines {
+				if re.MatchString(line) == opts.Invert {
+					continue
+				}
+				if opts.Field == "content" {
+					fmt.Fprintf(buf, "%s:%d:%s\n", refs[entry.Key], n+1, line)
+				} else {
+					fmt.Fprintf(buf, "%s:%s\n", refs[entry.Key], line)
+				}
+			}
+			continue
+		}
+		// Inverted, all lines have to miss
+		matched := false
+		for _, line := range lines {
+			if re.MatchString(line) {
+				matched = true
+				break
+			}
+		}
+		if matched != opts.Invert {
+			found = append(found, entry)
+		}
+	}
+	if opts.OnlyMatching {
+		return &Pipeline{
+			Reader: buf,
+		}
+	}
+
+	filtered, err := Marshal(found)
+	if err != nil {
+		return &Pipeline{
+			Error: MaskedError{
+				Prefix: "✘ error ... ",
+				Err:    err,
+			},
+		}
+	}
+
+	return &Pipeline{
+		Reader: filtered,
+	}
+}
@@ -0,0 +1,77 @@
+package pipeline_test
+
+import (
+	"io"
+	"pipeline"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMatch(t *testing.T) {
+	t.Parallel()
+	idxFile, key := tempStore(t)
+	addMemo(t, idxFile, "01G7H0000000000000000000E1", []string{"logs"}, "starting\npanic: nil map\ngoroutine 1\n")
+	tests := []struct {
+		pattern string
+		opts    pipeline.MatchOptions
+		want    []string
+	}{
+		{`^panic:`, pipeline.MatchOptions{}, []string{"01G7H0000000000000000000E1"}},
+		{`(?i)^hello`, pipeline.MatchOptions{}, []string{key}},
+		{`^panic:`, pipeline.MatchOptions{Invert: true}, []string{key}},
+		{`^log`, pipeline.MatchOptions{Field: "tags"}, []string{"01G7H0000000000000000000E1"}},
+		{`^09\.07\.2022`, pipeline.MatchOptions{Field: "modified"}, []string{key}},
+	}
+	for _, test := range tests {
+		f := pipeline.From(pipeline.PeriodAll, idxFile)
+		if f.Error.Err != nil {
+			t.Fatalf("want no error from From, got %q", f.Error.Err)
+		}
+		p := pipeline.Match(f.Reader, test.pattern, test.opts, idxFile)
+		if p.Error.Err != nil {
+			t.Fatalf("want no error from Match, got %q", p.Error.Err)
+		}
+		found, err := pipeline.ReadStream(p.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := []string{}
+		for _, entry := range found {
+			got = append(got, entry.Key)
+		}
+		if !cmp.Equal(test.want, got) {
+			t.Errorf("%q %+v: %s", test.pattern, test.opts, cmp.Diff(test.want, got))
+		}
+	}
+}
+
+func TestMatchOnlyMatching(t *testing.T) {
+	t.Parallel()
+	idxFile, _ := tempStore(t)
+	addMemo(t, idxFile, "01G7H0000000000000000000E1", []string{"logs"}, "starting\npanic: nil map\ngoroutine 1\n")
+	f := pipeline.From(pipeline.PeriodAll, idxFile)
+	if f.Error.Err != nil {
+		t.Fatalf("want no error from From, got %q", f.Error.Err)
+	}
+	p := pipeline.Match(f.Reader, `panic|world`, pipeline.MatchOptions{OnlyMatching: true}, idxFile)
+	if p.Error.Err != nil {
+		t.Fatalf("want no error from Match, got %q", p.Error.Err)
+	}
+	got, err := io.ReadAll(p.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "01G7GV:1:Hello world\n01G7H0:2:panic: nil map\n"
+	if !cmp.Equal(want, string(got)) {
+		t.Error(cmp.Diff(want, string(got)))
+	}
+}
+
+func TestMatchInvalid(t *testing.T) {
+	t.Parallel()
+	p := pipeline.Match(nil, `(unclosed`, pipeline.MatchOptions{}, "")
+	if p.Error.Err == nil {
+		t.Error("want error for invalid regexp, got none")
+	}
+}
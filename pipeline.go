@@ -3,12 +3,16 @@ package pipeline
 import (
 	"bufio"
 	"bytes"
+	"crypto/rand"
 	"crypto/sha256"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -23,15 +27,19 @@ type (
 
 	// Memo ... describes a default memo containing every kind of information
 	Memo struct {
+		ID       string // stable and time-sortable, given once by memo
+		Checksum string // sha256 of the content, integrity only
 		Tags     []string
 		Modified string // maybe created or updated
 		Content  string
 	}
 
 	IndexEntry struct {
+		Checksum string
 		Tags     map[string]bool
 		Path     string
 		Modified string
+		Deleted  string `json:",omitempty"` // moved into the trash
 	}
 
 	Index struct {
@@ -43,6 +51,18 @@ type (
 		Err    error
 	}
 
+	// TagError ... a tag, which can't be normalised
+	TagError struct {
+		Tag    string
+		Reason string
+	}
+
+	// AmbiguousError ... a memo reference matching more than one memo
+	AmbiguousError struct {
+		Ref        string
+		Candidates []string
+	}
+
 	Period string
 )
 
@@ -85,6 +105,16 @@ var (
 const (
 	basePath  = "~/.local/share/memo"
 	indexFile = "index.dat"
+	trashDir  = "trash"
+
+	// Layout of all timestamps within memos and index entries
+	timeLayout = "02.01.2006 15:04:05"
+
+	// Crockford's base32 alphabet for IDs
+	idAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+	// Shortest prefix printed as a memo reference
+	minPrefix = 6
 
 	// Time periods for Get
 	PeriodAll           Period = "all"
@@ -109,7 +139,17 @@ const (
 // ToJSON ... it's more a "from stdin to stdout as json"
 // used by memo
 //////////////////////////////////////////////////////////
-func ToJSON(rd io.Reader) *Pipeline {
+func ToJSON(rd io.Reader, filePath string) *Pipeline {
+	cfg, err := LoadConfig(filePath)
+	if err != nil {
+		return &Pipeline{
+			Error: MaskedError{
+				Prefix: "✘ error ... ",
+				Err:    err,
+			},
+		}
+	}
+	h := &hashtagger{cfg: cfg.Hashtags}
 	content := &bytes.Buffer{}
 	reader := bufio.NewReader(rd)
 	scanner := bufio.NewScanner(reader)
@@ -117,15 +157,40 @@ func ToJSON(rd io.Reader) *Pipeline {
 	for scanner.Scan() {
 		line := scanner.Text()
 		// >>> place to do anything with the line >>>
-		/// line = strings.ToUpper(line)
+		if !cfg.Hashtags.Off {
+			line = h.scan(line)
+		}
 		// <<< place to do anything with the line <<<
 		fmt.Fprintln(content, line)
 	}
-	// Build a memo
+	tags, err := NormalizeTags(h.tags, cfg)
+	if err != nil {
+		return &Pipeline{
+			Error: MaskedError{
+				Prefix: "✘ error ... ",
+				Err:    err,
+			},
+		}
+	}
+	// Build a memo with a fresh ID
+	now := time.Now()
+	id, err := NewID(now)
+	if err != nil {
+		return &Pipeline{
+			Error: MaskedError{
+				Prefix: "✘ error ... ",
+				Err:    err,
+			},
+		}
+	}
 	memo := Memo{
-		Modified: time.Now().Format("02.01.2006 15:04:05"),
+		ID:       id,
+		Modified: now.Format(timeLayout),
 		Content:  content.String(),
 	}
+	if len(tags) > 0 {
+		memo.Tags = tags
+	}
 	// Marshal the structured memo to JSON
 	r, err := Marshal(memo)
 	if err != nil {
@@ -142,10 +207,12 @@ func ToJSON(rd io.Reader) *Pipeline {
 }
 
 //////////////////////////////////////////////////////////
-// TagIt ... add a tag to an existing memo
+// TagIt ... add tags to an existing memo, every tag may be
+// a comma separated list, all tags get normalised, sorted
+// and duplicates are dropped
 // used by tagit
 //////////////////////////////////////////////////////////
-func TagIt(rd io.Reader, tag string) *Pipeline {
+func TagIt(rd io.Reader, filePath string, tags ...string) *Pipeline {
 	memo := Memo{}
 	err := Unmarshal(rd, &memo)
 	if err != nil {
@@ -156,9 +223,24 @@ func TagIt(rd io.Reader, tag string) *Pipeline {
 			},
 		}
 	}
+	cfg, err := LoadConfig(filePath)
+	if err != nil {
+		return &Pipeline{
+			Error: MaskedError{
+				Prefix: "✘ error ... ",
+				Err:    err,
+			},
+		}
+	}
 	// Tag the memo
-	if tag != "" {
-		memo.Tags = append(memo.Tags, tag)
+	memo.Tags, err = NormalizeTags(append(memo.Tags, tags...), cfg)
+	if err != nil {
+		return &Pipeline{
+			Error: MaskedError{
+				Prefix: "✘ error ... ",
+				Err:    err,
+			},
+		}
 	}
 	// Marshal the structured memo back to JSON
 	r, err := Marshal(memo)
@@ -182,6 +264,10 @@ func TagIt(rd io.Reader, tag string) *Pipeline {
 func Keep(rd io.Reader) {
 	memo := Memo{}
 	err := Unmarshal(rd, &memo)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "✘ error ... %q\n", err)
+		os.Exit(1)
+	}
 	// Configure & create the path for the memo
 	filePath, err := memo.GetPath(basePath)
 	if err != nil {
@@ -189,16 +275,50 @@ func Keep(rd io.Reader) {
 		os.Exit(1)
 	}
 	os.MkdirAll(filePath, os.ModePerm)
-	// Hash the content as part of the filename
-	hash, err := memo.Hash()
+	// Memos from older tools come without an ID
+	if memo.ID == "" {
+		memo.ID, err = memo.NewID()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "✘ error ... %q\n", err)
+			os.Exit(1)
+		}
+	}
+	// Tag the memo by the rules of the store
+	rules, err := LoadRules("")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "✘ error ... %q\n", err)
+		os.Exit(1)
+	}
+	cfg, err := LoadConfig("")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "✘ error ... %q\n", err)
+		os.Exit(1)
+	}
+	hits, err := memo.ApplyRules(rules, cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "✘ error ... %q\n", err)
+		os.Exit(1)
+	}
+	reportRules(memo.ID, hits)
+	// Hash the content for integrity checks
+	memo.Checksum, err = memo.Hash()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "✘ error ... %q\n", err)
 		os.Exit(1)
 	}
-	fmt.Fprintf(os.Stderr, "✓ hashed ... %s\n", hash)
+	fmt.Fprintf(os.Stderr, "✓ hashed ... %s\n", memo.Checksum)
 
 	// Fill the index
 	idxFile := TakeMeHome(basePath + string(os.PathSeparator) + indexFile)
+	// Old indexes are keyed by hashes, give them IDs first
+	migrated, err := Migrate(idxFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "✘ error ... %q\n", err)
+		os.Exit(1)
+	}
+	if migrated > 0 {
+		fmt.Fprintf(os.Stderr, "✓ migrated ... %d entries\n", migrated)
+	}
 	// Loading the index from file
 	idx := NewIndex()
 	err = idx.Load(idxFile)
@@ -206,37 +326,120 @@ func Keep(rd io.Reader) {
 		fmt.Fprintf(os.Stderr, "✘ error ... %q\n", err)
 		os.Exit(1)
 	}
-	defer idx.Store(idxFile)
-	idxEntry := IndexEntry{
-		Tags:     make(map[string]bool),
-		Path:     filePath,
-		Modified: memo.Modified,
-	}
-	for _, tag := range memo.Tags {
-		idxEntry.Tags[tag] = true
-	}
-	before := len(idx.entries)
-	idx.Upsert(hash, idxEntry)
-	after := len(idx.entries)
-	if before < after {
-		fmt.Fprintf(os.Stderr, "✓ index ... added, %d entries now\n", after)
+	previous, known := idx.entries[memo.ID]
+	idx.Upsert(memo.ID, memo.entry(filePath))
+	if !known {
+		fmt.Fprintf(os.Stderr, "✓ index ... added %s, %d entries now\n", memo.ID, len(idx.entries))
 	} else {
-		fmt.Fprintf(os.Stderr, "✓ index ... updated, %d entries\n", after)
+		fmt.Fprintf(os.Stderr, "✓ index ... updated %s, %d entries\n", memo.ID, len(idx.entries))
+		// Keep the prior version instead of overwriting it
+		rev, err := archive(TakeMeHome(basePath), memo.ID, previous.Path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "✘ error ... %q\n", err)
+			os.Exit(1)
+		}
+		if previous.Path != filePath {
+			os.Remove(previous.Path + string(os.PathSeparator) + memo.ID + ".memo")
+		}
+		fmt.Fprintf(os.Stderr, "✓ history ... revision %d kept\n", rev)
 	}
-	// Find a file for the memo
-	fi, err := os.Create(filePath + string(os.PathSeparator) + hash + ".memo")
+	// Finally write the Memo
+	err = memo.save(filePath, memo.ID)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "✘ error ... %q\n", err)
 		os.Exit(1)
 	}
-	defer fi.Close()
-	// Finally write the Memo
-	err = memo.Write(fi)
+	// The index must know the memo, before anything else may fail
+	err = idx.Store(idxFile)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "✘ error ... %q\n", err)
 		os.Exit(1)
 	}
 	fmt.Fprintln(os.Stderr, "✓ stored")
+	// Make the content searchable
+	err = NewTermIndex().Update(termsPath(idxFile), func(t *TermIndex) error {
+		t.Add(memo.ID, memo.Content)
+		return nil
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "✘ error ... %q\n", err)
+		os.Exit(1)
+	}
+	fmt.Fprintln(os.Stderr, "✓ terms ... indexed")
+}
+
+//////////////////////////////////////////////////////////
+// Migrate ... rekeys an index from content hashes to IDs
+// and renames the memo files accordingly, returns the
+// number of migrated entries
+// used by keep & migrate
+//////////////////////////////////////////////////////////
+func Migrate(filePath string) (int, error) {
+	if filePath == "" {
+		filePath = TakeMeHome(basePath + string(os.PathSeparator) + indexFile)
+	}
+	idx := NewIndex()
+	err := idx.Load(filePath)
+	if err != nil {
+		return 0, err
+	}
+	migrated := 0
+	rekeyed := make(map[string]Memo)
+	for key, entry := range idx.entries {
+		if !isLegacyKey(key) {
+			continue
+		}
+		oldFile := entry.Path + string(os.PathSeparator) + key + ".memo"
+		fi, err := os.Open(oldFile)
+		if err != nil {
+			return migrated, err
+		}
+		memo := Memo{}
+		err = memo.Read(fi)
+		fi.Close()
+		if err != nil {
+			return migrated, err
+		}
+		// IDs of migrated memos carry their original timestamp
+		memo.ID, err = memo.NewID()
+		if err != nil {
+			return migrated, err
+		}
+		memo.Checksum = key
+		newFile := entry.Path + string(os.PathSeparator) + memo.ID + ".memo"
+		fo, err := os.Create(newFile)
+		if err != nil {
+			return migrated, err
+		}
+		err = memo.Write(fo)
+		fo.Close()
+		if err != nil {
+			return migrated, err
+		}
+		err = os.Remove(oldFile)
+		if err != nil {
+			return migrated, err
+		}
+		entry.Checksum = key
+		idx.Upsert(memo.ID, entry)
+		idx.Delete(key)
+		rekeyed[key] = memo
+		migrated++
+	}
+	if migrated == 0 {
+		return 0, nil
+	}
+	err = idx.Store(filePath)
+	if err != nil {
+		return migrated, err
+	}
+	return migrated, NewTermIndex().Update(termsPath(filePath), func(t *TermIndex) error {
+		for key, memo := range rekeyed {
+			t.Remove(key)
+			t.Add(memo.ID, memo.Content)
+		}
+		return nil
+	})
 }
 
 ////////////////////////////////////////////////////////////////
@@ -251,10 +454,12 @@ func From(period Period, filePath string) *Pipeline {
 		fmt.Fprintln(os.Stderr, "FROM is part of memo's famous toolbox")
 		fmt.Fprintln(os.Stderr, "-------------------------------------")
 		fmt.Fprintln(os.Stderr, "Usage: from [<period>] | further tools")
+		fmt.Fprintln(os.Stderr, "       from @<name> | further tools")
 		fmt.Fprintln(os.Stderr, "       from help")
 		fmt.Fprintln(os.Stderr)
 		fmt.Fprintln(os.Stderr, "It reads an index file for memos. Entries get filtered by periods of time. The")
 		fmt.Fprintln(os.Stderr, "periods are described verbally. Dates or timestamps are not supported.")
+		fmt.Fprintln(os.Stderr, "Memos in the trash are left out, see trash.")
 		fmt.Fprintln(os.Stderr)
 		fmt.Fprintln(os.Stderr, "Following periods are supported by now ...")
 		fmt.Fprintln(os.Stderr, "  ✓ all (default)")
@@ -270,12 +475,15 @@ func From(period Period, filePath string) *Pipeline {
 		fmt.Fprintln(os.Stderr, "  ✘ lastyear")
 		fmt.Fprintln(os.Stderr, "  ✘ last2years")
 		fmt.Fprintln(os.Stderr)
-		fmt.Fprintln(os.Stderr, "Memo's toolbox contains these tool chains ...")
-		fmt.Fprintln(os.Stderr, "  ... memo, taggit, keep")
-		fmt.Fprintln(os.Stderr, "  ... from, tagged, stdout")
+		fmt.Fprintln(os.Stderr, "@name runs a search saved by query instead.")
 		fmt.Fprintln(os.Stderr)
+		toolbox()
 		os.Exit(0)
 	}
+	// Saved searches are up to query
+	if strings.HasPrefix(string(period), "@") {
+		return Query(filePath, string(period))
+	}
 	// Check the period
 	if !validPeriod[period] {
 		return &Pipeline{
@@ -313,11 +521,17 @@ func From(period Period, filePath string) *Pipeline {
 			},
 		}
 	}
+	// Trashed entries are gone for everybody else
+	for key, entry := range entries {
+		if entry.Deleted != "" {
+			delete(entries, key)
+		}
+	}
 
 	// Run the period filter over the map
 	switch period {
 	case PeriodAll:
-		r, err := Marshal(entries)
+		r, err := Marshal(NewStream(entries))
 		if err != nil {
 			return &Pipeline{
 				Error: MaskedError{
@@ -347,13 +561,14 @@ func From(period Period, filePath string) *Pipeline {
 // Tagged ... reduces an index to tagged entries only
 // used by tagged
 //////////////////////////////////////////////////////////
-func Tagged(rd io.Reader, tags ...string) *Pipeline {
+func Tagged(rd io.Reader, filePath string, tags ...string) *Pipeline {
 	// Help wanted?
 	if len(tags) > 0 && tags[0] == "help" {
 		fmt.Fprintln(os.Stderr)
 		fmt.Fprintln(os.Stderr, "TAGGED is part of memo's famous toolbox")
 		fmt.Fprintln(os.Stderr, "---------------------------------------")
-		fmt.Fprintln(os.Stderr, "Usage: tagged <tag> [<tag2> <tag3> <tagn>] | further tools")
+		fmt.Fprintln(os.Stderr, "Usage: tagged [--exact] <tag> [<tag2> <tag3> <tagn>] | further tools")
+		fmt.Fprintln(os.Stderr, "       tagged [--exact] '<expression>' | further tools")
 		fmt.Fprintln(os.Stderr, "       tagged help")
 		fmt.Fprintln(os.Stderr)
 		fmt.Fprintln(os.Stderr, "It takes a list of index entries for memos from the pipe. They")
@@ -361,14 +576,28 @@ func Tagged(rd io.Reader, tags ...string) *Pipeline {
 		fmt.Fprintln(os.Stderr, "which are tagged with all tags, will survive and get piped to the")
 		fmt.Fprintln(os.Stderr, "next tool in the chain.")
 		fmt.Fprintln(os.Stderr)
-		fmt.Fprintln(os.Stderr, "Memo's toolbox contains these tool chains ...")
-		fmt.Fprintln(os.Stderr, "  ... memo, taggit, keep")
-		fmt.Fprintln(os.Stderr, "  ... from, tagged, stdout")
+		fmt.Fprintln(os.Stderr, "Tags may be hierarchical like project/alpha/design. A tag matches")
+		fmt.Fprintln(os.Stderr, "all its descendants, so project/alpha finds project/alpha/design")
+		fmt.Fprintln(os.Stderr, "as well. With --exact only the tag itself matches.")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Tags may be combined by and, or, not and parentheses as well ...")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "  tagged 'work and (urgent or blocked) and not done'")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Not binds before and, and binds before or. Tags next to each other")
+		fmt.Fprintln(os.Stderr, "without operator mean and. Only a quoted expression gets parsed,")
+		fmt.Fprintln(os.Stderr, "separate arguments like and, or and not stay plain tags. Each")
+		fmt.Fprintln(os.Stderr, "argument is parsed on its own and all of them must match.")
 		fmt.Fprintln(os.Stderr)
+		toolbox()
 		os.Exit(0)
 	}
-	entries := make(map[string]IndexEntry)
-	err := Unmarshal(rd, &entries)
+	exact := false
+	if len(tags) > 0 && tags[0] == "--exact" {
+		exact = true
+		tags = tags[1:]
+	}
+	cfg, err := LoadConfig(filePath)
 	if err != nil {
 		return &Pipeline{
 			Error: MaskedError{
@@ -377,18 +606,29 @@ func Tagged(rd io.Reader, tags ...string) *Pipeline {
 			},
 		}
 	}
-
-	found := make(map[string]IndexEntry)
-	for key, value := range entries {
-		hit := true
-		for _, tag := range tags {
-			if !value.Tags[tag] {
-				hit = false
-				break
-			}
+	expr, err := parseTagArgs(tags, cfg)
+	if err != nil {
+		return &Pipeline{
+			Error: MaskedError{
+				Prefix: "✘ error ... ",
+				Err:    err,
+			},
+		}
+	}
+	entries, err := ReadStream(rd)
+	if err != nil {
+		return &Pipeline{
+			Error: MaskedError{
+				Prefix: "✘ error ... ",
+				Err:    err,
+			},
 		}
-		if hit {
-			found[key] = value
+	}
+
+	found := Stream{}
+	for _, entry := range entries {
+		if expr.Match(entry.normalizedTags(cfg), exact) {
+			found = append(found, entry)
 		}
 	}
 
@@ -412,13 +652,13 @@ func Tagged(rd io.Reader, tags ...string) *Pipeline {
 // selection will be triggered by incoming index entries
 // used by stdout
 //////////////////////////////////////////////////////////
-func Stdout(rd io.Reader, what string) *Pipeline {
+func Stdout(rd io.Reader, what string, limit int, filePath string) *Pipeline {
 	// Help wanted?
 	if what == "help" {
 		fmt.Fprintln(os.Stderr)
 		fmt.Fprintln(os.Stderr, "STDOUT is part of memo's famous toolbox")
 		fmt.Fprintln(os.Stderr, "---------------------------------------")
-		fmt.Fprintln(os.Stderr, "Usage: stdout [<what>]")
+		fmt.Fprintln(os.Stderr, "Usage: stdout [<what>] [--limit <n>]")
 		fmt.Fprintln(os.Stderr, "       stdout help")
 		fmt.Fprintln(os.Stderr)
 		fmt.Fprintln(os.Stderr, "It takes a list of index entries for memos from the pipe. Next it")
@@ -427,6 +667,10 @@ func Stdout(rd io.Reader, what string) *Pipeline {
 		fmt.Fprintln(os.Stderr, "from the memos to stdout in the terminal. The output may be")
 		fmt.Fprintln(os.Stderr, "piped to any other tools as well, but memo's tool chain ends here.")
 		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Memos come in the order of the pipe, so search results print the")
+		fmt.Fprintln(os.Stderr, "most relevant first. With --limit only the first n get printed. Scores")
+		fmt.Fprintln(os.Stderr, "of search or similar follow the ref.")
+		fmt.Fprintln(os.Stderr)
 		fmt.Fprintln(os.Stderr, "Following output formats taken by <what> are supported by now ...")
 		//   short   ... memo only (default)
 		//   long    ... all fields
@@ -435,18 +679,14 @@ func Stdout(rd io.Reader, what string) *Pipeline {
 		fmt.Fprintln(os.Stderr, "  ✓ long ... all fields")
 		fmt.Fprintln(os.Stderr, "  ✓ verbose ... all fields with labels")
 		fmt.Fprintln(os.Stderr)
-		fmt.Fprintln(os.Stderr, "Memo's toolbox contains these tool chains ...")
-		fmt.Fprintln(os.Stderr, "  ... memo, taggit, keep")
-		fmt.Fprintln(os.Stderr, "  ... from, tagged, stdout")
-		fmt.Fprintln(os.Stderr)
+		toolbox()
 		os.Exit(0)
 	}
 	//////////////////////////////////////////////////////
 	// Handle input vi pipe from previous process
 	// Should be a list of index entries
 	//////////////////////////////////////////////////////
-	idx := map[string]IndexEntry{}
-	err := Unmarshal(rd, &idx)
+	stream, err := ReadStream(rd)
 	if err != nil {
 		return &Pipeline{
 			Error: MaskedError{
@@ -459,9 +699,11 @@ func Stdout(rd io.Reader, what string) *Pipeline {
 	// Load memos from files
 	// coordinates come from index entries
 	//////////////////////////////////////////////////////
-	memos := []Memo{}
-	for fileName, details := range idx {
-		fi, err := os.Open(details.Path + string(os.PathSeparator) + fileName + ".memo")
+	// Shortest unique prefixes as references for the output
+	refs := RefIndex(stream.Entries(), filePath).Prefixes()
+	buf := &bytes.Buffer{}
+	for _, details := range stream.Limit(limit) {
+		fi, err := os.Open(details.Path + string(os.PathSeparator) + details.Key + ".memo")
 		if err != nil {
 			return &Pipeline{
 				Error: MaskedError{
@@ -470,9 +712,9 @@ func Stdout(rd io.Reader, what string) *Pipeline {
 				},
 			}
 		}
-		defer fi.Close()
 		memo := Memo{}
 		err = Unmarshal(fi, &memo)
+		fi.Close()
 		if err != nil {
 			return &Pipeline{
 				Error: MaskedError{
@@ -481,50 +723,17 @@ func Stdout(rd io.Reader, what string) *Pipeline {
 				},
 			}
 		}
-		memos = append(memos, memo)
-	}
-	buf := &bytes.Buffer{}
-	for _, memo := range memos {
-
-		//////////////////////////////////////////////////////////////////////
-		// Switch verbosity of the output
-		//   short   ... memo only (default)
-		//   long    ... all fields
-		//   verbose ... all fields with labels
-		//////////////////////////////////////////////////////////////////////
-		switch what {
-		case "long":
-			_, err := fmt.Fprintf(buf, "\n%s\n%s", memo.Modified, memo.Content)
-			if err != nil {
-				return &Pipeline{
-					Error: MaskedError{
-						Prefix: "✘ error ... ",
-						Err:    err,
-					},
-				}
-			}
-		case "verbose":
-			_, err := fmt.Fprintf(buf, "\nModified: %s\nTags: %s\nMemo: %s",
-				memo.Modified,
-				strings.Join(memo.Tags, ", "),
-				memo.Content)
-			if err != nil {
-				return &Pipeline{
-					Error: MaskedError{
-						Prefix: "✘ error ... ",
-						Err:    err,
-					},
-				}
-			}
-		default:
-			_, err := fmt.Fprintf(buf, "\n%s", memo.Content)
-			if err != nil {
-				return &Pipeline{
-					Error: MaskedError{
-						Prefix: "✘ error ... ",
-						Err:    err,
-					},
-				}
+		ref := refs[details.Key]
+		if details.Score != 0 {
+			ref = fmt.Sprintf("%s %.2f", ref, details.Score)
+		}
+		err = memo.Print(buf, what, ref)
+		if err != nil {
+			return &Pipeline{
+				Error: MaskedError{
+					Prefix: "✘ error ... ",
+					Err:    err,
+				},
 			}
 		}
 	}
@@ -534,89 +743,730 @@ func Stdout(rd io.Reader, what string) *Pipeline {
 }
 
 //////////////////////////////////////////////////////////
-// TakeMeHome ... replaces the leading ~ in a path with
-// user's home dir
+// RefIndex ... the whole index as far as it is known to
+// resolve references, completed by the entries at hand,
+// the index lives at filePath
 //////////////////////////////////////////////////////////
-func TakeMeHome(path string) string {
-	if string(path[0]) != "~" {
-		return path
+func RefIndex(entries map[string]IndexEntry, filePath string) *Index {
+	if filePath == "" {
+		filePath = TakeMeHome(basePath + string(os.PathSeparator) + indexFile)
 	}
-	home := os.Getenv("HOME")
-	if home == "" {
-		return path
+	idx := NewIndex()
+	// Without a readable index the entries at hand have to do
+	idx.Load(filePath)
+	for key, entry := range entries {
+		idx.Upsert(key, entry)
 	}
-	path = home + path[1:]
-	return path
+	return idx
 }
 
-//////////////////////////////////////////////////////
-// PIPELINE
-//////////////////////////////////////////////////////
-
-// Stdout ... Universal output, per default to Stdout
-// but it could be any other io.Writer, too
-func (p *Pipeline) Stdout() {
-	if p.Error.Err != nil {
-		return
+//////////////////////////////////////////////////////////
+// Show ... prints a single memo by its reference
+// used by show
+//////////////////////////////////////////////////////////
+func Show(ref, what, filePath string) *Pipeline {
+	// Help wanted?
+	if ref == "help" {
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "SHOW is part of memo's famous toolbox")
+		fmt.Fprintln(os.Stderr, "-------------------------------------")
+		fmt.Fprintln(os.Stderr, "Usage: show <ref>[@<revision>] [<what>]")
+		fmt.Fprintln(os.Stderr, "       show help")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "It looks up a single memo in the index and prints it to stdout. The")
+		fmt.Fprintln(os.Stderr, "reference is the memo's ID or its content hash, any unique prefix")
+		fmt.Fprintln(os.Stderr, "of them will do as well. Older revisions are selected by @<n>, see")
+		fmt.Fprintln(os.Stderr, "history for the list. Memos in the trash are marked as such.")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Following output formats taken by <what> are supported by now ...")
+		fmt.Fprintln(os.Stderr, "  ✓ short ... memo only (default)")
+		fmt.Fprintln(os.Stderr, "  ✓ long ... all fields")
+		fmt.Fprintln(os.Stderr, "  ✓ verbose ... all fields with labels")
+		fmt.Fprintln(os.Stderr)
+		toolbox()
+		os.Exit(0)
 	}
-	io.Copy(p.Output, p.Reader)
-	// Cosmetic improvements at the output
-	lineBreak := bytes.Buffer{}
-	lineBreak.WriteString("\n")
-	io.Copy(p.Output, &lineBreak)
-}
-
-//////////////////////////////////////////////////////
-// INDEX
-//////////////////////////////////////////////////////
-
-// NewIndex ... Constructor for Index
-func NewIndex() *Index {
-	entries := make(map[string]IndexEntry)
-	return &Index{entries: entries}
-}
-
-// Upsert ... Inserts a new entry or overwrites an existing entry in the index
-func (i *Index) Upsert(key string, e IndexEntry) {
-	i.entries[key] = e
-}
-
-// Delete ... Removes an entry from the index
-func (i *Index) Delete(key string) error {
-	_, exist := i.entries[key]
-	if !exist {
-		return fmt.Errorf("index entry for key %s does not exist", key)
+	if filePath == "" {
+		filePath = TakeMeHome(basePath + string(os.PathSeparator) + indexFile)
 	}
-	delete(*&i.entries, key)
-	return nil
-}
-
-// Find ... Looks for an entry in the index by key
-func (i *Index) Find(key string) IndexEntry {
-	return i.entries[key]
-}
-
-// Store ... Stores entries into file at path
-func (i *Index) Store(path string) error {
-	lock.Lock()
-	defer lock.Unlock()
-	f, err := os.Create(path)
+	idx := NewIndex()
+	err := idx.Load(filePath)
 	if err != nil {
-		return err
+		return &Pipeline{
+			Error: MaskedError{
+				Prefix: "✘ error ... ",
+				Err:    err,
+			},
+		}
 	}
-	defer f.Close()
-	r, err := Marshal(i.entries)
+	ref, rev, err := SplitRevision(ref)
+	if err != nil {
+		return &Pipeline{
+			Error: MaskedError{
+				Prefix: "✘ error ... ",
+				Err:    err,
+			},
+		}
+	}
+	key, err := idx.Resolve(ref)
+	if err != nil {
+		return &Pipeline{
+			Error: MaskedError{
+				Prefix: "✘ error ... ",
+				Err:    err,
+			},
+		}
+	}
+	memo, err := revision(filepath.Dir(filePath), key, idx.Find(key), rev)
+	if err != nil {
+		return &Pipeline{
+			Error: MaskedError{
+				Prefix: "✘ error ... ",
+				Err:    err,
+			},
+		}
+	}
+	prefix := idx.Prefixes()[key]
+	if deleted := idx.Find(key).Deleted; deleted != "" {
+		fmt.Fprintf(os.Stderr, "✓ trash ... %s is in the trash since %s, restore brings it back\n", prefix, deleted)
+		prefix += " (trash)"
+	}
+	buf := &bytes.Buffer{}
+	err = memo.Print(buf, what, prefix)
+	if err != nil {
+		return &Pipeline{
+			Error: MaskedError{
+				Prefix: "✘ error ... ",
+				Err:    err,
+			},
+		}
+	}
+	return &Pipeline{
+		Reader: buf,
+	}
+}
+
+//////////////////////////////////////////////////////////
+// Forget ... moves memos into the trash and flags their
+// index entries as deleted, the memos are given by
+// references or, if there are none, by an index stream,
+// confirm decides about the deletion and may be nil
+// used by forget
+//////////////////////////////////////////////////////////
+func Forget(rd io.Reader, refs []string, filePath string, dryRun bool, confirm func(list []string) bool) *Pipeline {
+	// Help wanted?
+	if len(refs) > 0 && refs[0] == "help" {
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "FORGET is part of memo's famous toolbox")
+		fmt.Fprintln(os.Stderr, "---------------------------------------")
+		fmt.Fprintln(os.Stderr, "Usage: forget [--yes] [--dry-run] <ref> [<ref2> <ref3> <refn>]")
+		fmt.Fprintln(os.Stderr, "       further tools | forget [--yes] [--dry-run]")
+		fmt.Fprintln(os.Stderr, "       forget help")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "It moves memos into the trash, where they wait for restore or for the")
+		fmt.Fprintln(os.Stderr, "trash to be emptied. The memos are given by references, which are IDs,")
+		fmt.Fprintln(os.Stderr, "content hashes or any unique prefix of them. Without references it takes")
+		fmt.Fprintln(os.Stderr, "a list of index entries from the pipe. Before it asks for confirmation.")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Following options are supported by now ...")
+		fmt.Fprintln(os.Stderr, "  ✓ --yes ... no confirmation needed")
+		fmt.Fprintln(os.Stderr, "  ✓ --dry-run ... list the memos only, nothing gets trashed")
+		fmt.Fprintln(os.Stderr)
+		toolbox()
+		os.Exit(0)
+	}
+	if filePath == "" {
+		filePath = TakeMeHome(basePath + string(os.PathSeparator) + indexFile)
+	}
+	idx := NewIndex()
+	err := idx.Load(filePath)
+	if err != nil {
+		return &Pipeline{
+			Error: MaskedError{
+				Prefix: "✘ error ... ",
+				Err:    err,
+			},
+		}
+	}
+	// Collect the doomed entries
+	doomed := make(map[string]IndexEntry)
+	if len(refs) > 0 {
+		for _, ref := range refs {
+			key, err := idx.Resolve(ref)
+			if err != nil {
+				return &Pipeline{
+					Error: MaskedError{
+						Prefix: "✘ error ... ",
+						Err:    err,
+					},
+				}
+			}
+			doomed[key] = idx.Find(key)
+		}
+	} else {
+		stream, err := ReadStream(rd)
+		if err != nil {
+			return &Pipeline{
+				Error: MaskedError{
+					Prefix: "✘ error ... ",
+					Err:    err,
+				},
+			}
+		}
+		doomed = stream.Entries()
+	}
+	// List them in order of their IDs
+	prefixes := idx.Prefixes()
+	keys := make([]string, 0, len(doomed))
+	for key := range doomed {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	list := []string{}
+	for _, key := range keys {
+		ref := prefixes[key]
+		if ref == "" {
+			ref = key
+		}
+		list = append(list, fmt.Sprintf("%s %s %s", ref, doomed[key].Modified, strings.Join(doomed[key].SortedTags(), ", ")))
+	}
+	buf := bytes.NewBufferString(strings.Join(list, "\n"))
+	if dryRun || len(keys) == 0 {
+		return &Pipeline{
+			Reader: buf,
+		}
+	}
+	if confirm != nil && !confirm(list) {
+		return &Pipeline{
+			Error: MaskedError{
+				Prefix: "✘ error ... ",
+				Err:    errors.New("nothing forgotten, confirmation missing"),
+			},
+		}
+	}
+	// Move the files into the trash and flag their entries, moved files
+	// go back if the index can't be stored
+	trash := filepath.Join(filepath.Dir(filePath), trashDir)
+	moved := []move{}
+	err = NewIndex().Update(filePath, func(i *Index) error {
+		now := time.Now().Format(timeLayout)
+		for _, key := range keys {
+			entry, exist := i.entries[key]
+			if !exist {
+				return fmt.Errorf("index entry for key %s does not exist", key)
+			}
+			if entry.Deleted != "" {
+				continue
+			}
+			err := moveMemo(key, entry.Path, trash)
+			if err != nil {
+				return err
+			}
+			moved = append(moved, move{key, entry.Path, trash})
+			entry.Path = trash
+			entry.Deleted = now
+			i.Upsert(key, entry)
+		}
+		return nil
+	})
+	if err != nil {
+		undoMoves(moved)
+		return &Pipeline{
+			Error: MaskedError{
+				Prefix: "✘ error ... ",
+				Err:    err,
+			},
+		}
+	}
+	return &Pipeline{
+		Reader: buf,
+	}
+}
+
+//////////////////////////////////////////////////////////
+// Trash ... lists the memos in the trash as index stream
+// used by trash
+//////////////////////////////////////////////////////////
+func Trash(what, filePath string) *Pipeline {
+	// Help wanted?
+	if what == "help" {
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "TRASH is part of memo's famous toolbox")
+		fmt.Fprintln(os.Stderr, "--------------------------------------")
+		fmt.Fprintln(os.Stderr, "Usage: trash [list] | further tools")
+		fmt.Fprintln(os.Stderr, "       trash empty --older-than <age> | --all")
+		fmt.Fprintln(os.Stderr, "       trash help")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Forgotten memos are kept in the trash until it gets emptied. Listing")
+		fmt.Fprintln(os.Stderr, "the trash pipes its index entries to the next tool in the chain, so")
+		fmt.Fprintln(os.Stderr, "they may be printed by stdout. Memos come back by restore <ref>.")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "The age for emptying is given in days (30d), weeks (2w) or as Go")
+		fmt.Fprintln(os.Stderr, "duration (12h). Only --all empties the whole trash.")
+		fmt.Fprintln(os.Stderr)
+		toolbox()
+		os.Exit(0)
+	}
+	if filePath == "" {
+		filePath = TakeMeHome(basePath + string(os.PathSeparator) + indexFile)
+	}
+	idx := NewIndex()
+	err := idx.Load(filePath)
+	if err != nil {
+		return &Pipeline{
+			Error: MaskedError{
+				Prefix: "✘ error ... ",
+				Err:    err,
+			},
+		}
+	}
+	trashed := make(map[string]IndexEntry)
+	for key, entry := range idx.entries {
+		if entry.Deleted != "" {
+			trashed[key] = entry
+		}
+	}
+	r, err := Marshal(NewStream(trashed))
+	if err != nil {
+		return &Pipeline{
+			Error: MaskedError{
+				Prefix: "✘ error ... ",
+				Err:    err,
+			},
+		}
+	}
+	return &Pipeline{
+		Reader: r,
+	}
+}
+
+//////////////////////////////////////////////////////////
+// EmptyTrash ... purges memos from the trash, which were
+// deleted longer ago than olderThan, returns their number
+// used by trash
+//////////////////////////////////////////////////////////
+func EmptyTrash(olderThan time.Duration, filePath string) (int, error) {
+	if filePath == "" {
+		filePath = TakeMeHome(basePath + string(os.PathSeparator) + indexFile)
+	}
+	purged := []string{}
+	paths := []string{}
+	err := NewIndex().Update(filePath, func(i *Index) error {
+		for key, entry := range i.entries {
+			if entry.Deleted == "" {
+				continue
+			}
+			deleted, err := time.ParseInLocation(timeLayout, entry.Deleted, time.Local)
+			if err != nil {
+				return err
+			}
+			if time.Since(deleted) < olderThan {
+				continue
+			}
+			i.Delete(key)
+			purged = append(purged, key)
+			paths = append(paths, entry.Path+string(os.PathSeparator)+key+".memo")
+		}
+		return nil
+	})
+	if err != nil || len(purged) == 0 {
+		return 0, err
+	}
+	// The files go only, when the index doesn't know them anymore
+	for n, key := range purged {
+		err = os.Remove(paths[n])
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return len(purged), err
+		}
+		err = os.RemoveAll(filepath.Join(filepath.Dir(filePath), historyDir, key))
+		if err != nil {
+			return len(purged), err
+		}
+	}
+	return len(purged), NewTermIndex().Update(termsPath(filePath), func(t *TermIndex) error {
+		for _, key := range purged {
+			t.Remove(key)
+		}
+		return nil
+	})
+}
+
+//////////////////////////////////////////////////////////
+// Restore ... brings memos back from the trash
+// used by restore
+//////////////////////////////////////////////////////////
+func Restore(refs []string, filePath string) *Pipeline {
+	// Help wanted?
+	if len(refs) > 0 && refs[0] == "help" {
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "RESTORE is part of memo's famous toolbox")
+		fmt.Fprintln(os.Stderr, "----------------------------------------")
+		fmt.Fprintln(os.Stderr, "Usage: restore <ref> [<ref2> <ref3> <refn>]")
+		fmt.Fprintln(os.Stderr, "       restore help")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "It brings forgotten memos back from the trash to their old place.")
+		fmt.Fprintln(os.Stderr, "References are IDs, content hashes or any unique prefix of them.")
+		fmt.Fprintln(os.Stderr)
+		toolbox()
+		os.Exit(0)
+	}
+	if filePath == "" {
+		filePath = TakeMeHome(basePath + string(os.PathSeparator) + indexFile)
+	}
+	base := filepath.Dir(filePath)
+	buf := &bytes.Buffer{}
+	moved := []move{}
+	err := NewIndex().Update(filePath, func(i *Index) error {
+		// Only the trash counts for references
+		trashed := NewIndex()
+		for key, entry := range i.entries {
+			if entry.Deleted != "" {
+				trashed.Upsert(key, entry)
+			}
+		}
+		for _, ref := range refs {
+			key, err := trashed.Resolve(ref)
+			if err != nil {
+				return err
+			}
+			entry := i.entries[key]
+			memo := Memo{Modified: entry.Modified}
+			home, err := memo.GetPath(base)
+			if err != nil {
+				return err
+			}
+			err = moveMemo(key, entry.Path, home)
+			if err != nil {
+				return err
+			}
+			moved = append(moved, move{key, entry.Path, home})
+			entry.Path = home
+			entry.Deleted = ""
+			i.Upsert(key, entry)
+			fmt.Fprintf(buf, "%s %s\n", key, entry.Modified)
+		}
+		return nil
+	})
+	if err != nil {
+		undoMoves(moved)
+		return &Pipeline{
+			Error: MaskedError{
+				Prefix: "✘ error ... ",
+				Err:    err,
+			},
+		}
+	}
+	return &Pipeline{
+		Reader: buf,
+	}
+}
+
+//////////////////////////////////////////////////////////
+// ParseAge ... reads ages like 30d, 2w or Go durations
+//////////////////////////////////////////////////////////
+func ParseAge(age string) (time.Duration, error) {
+	units := map[string]time.Duration{
+		"d": 24 * time.Hour,
+		"w": 7 * 24 * time.Hour,
+	}
+	for suffix, unit := range units {
+		if strings.HasSuffix(age, suffix) {
+			n, err := strconv.Atoi(strings.TrimSuffix(age, suffix))
+			if err != nil {
+				return 0, fmt.Errorf("invalid age %q", age)
+			}
+			return time.Duration(n) * unit, nil
+		}
+	}
+	return time.ParseDuration(age)
+}
+
+// move ... a memo file moved between directories
+type move struct {
+	key, from, to string
+}
+
+// undoMoves ... moves memo files back, the last first
+func undoMoves(moved []move) {
+	for n := len(moved) - 1; n >= 0; n-- {
+		moveMemo(moved[n].key, moved[n].to, moved[n].from)
+	}
+}
+
+//////////////////////////////////////////////////////////
+// moveMemo ... moves a memo file between directories
+//////////////////////////////////////////////////////////
+func moveMemo(key, from, to string) error {
+	err := os.MkdirAll(to, os.ModePerm)
 	if err != nil {
 		return err
 	}
-	_, err = io.Copy(f, r)
-	return err
+	return os.Rename(
+		from+string(os.PathSeparator)+key+".memo",
+		to+string(os.PathSeparator)+key+".memo")
+}
+
+//////////////////////////////////////////////////////////
+// toolbox ... lists all tools in the help texts
+//////////////////////////////////////////////////////////
+func toolbox() {
+	fmt.Fprintln(os.Stderr, "Memo's toolbox contains these tool chains ...")
+	fmt.Fprintln(os.Stderr, "  ... memo, taggit, keep")
+	fmt.Fprintln(os.Stderr, "  ... memo, hashtags, keep")
+	fmt.Fprintln(os.Stderr, "  ... from, tagged, stdout")
+	fmt.Fprintln(os.Stderr, "  ... query, stdout")
+	fmt.Fprintln(os.Stderr, "  ... from, search, tagged, stdout")
+	fmt.Fprintln(os.Stderr, "  ... from, match --only-matching")
+	fmt.Fprintln(os.Stderr, "  ... from, fuzzy, stdout")
+	fmt.Fprintln(os.Stderr, "  ... from, tagged, retag, forget")
+	fmt.Fprintln(os.Stderr, "  ... from, tagged, tags")
+	fmt.Fprintln(os.Stderr, "  ... from, autotag, stdout")
+	fmt.Fprintln(os.Stderr, "  ... memo, suggest")
+	fmt.Fprintln(os.Stderr, "  ... memo, similar, stdout")
+	fmt.Fprintln(os.Stderr, "  ... similar, stdout")
+	fmt.Fprintln(os.Stderr, "  ... trash, stdout")
+	fmt.Fprintln(os.Stderr, "  ... show, edit, history, diff, restore")
+	fmt.Fprintln(os.Stderr)
+}
+
+//////////////////////////////////////////////////////////
+// TakeMeHome ... replaces the leading ~ in a path with
+// user's home dir
+//////////////////////////////////////////////////////////
+func TakeMeHome(path string) string {
+	if string(path[0]) != "~" {
+		return path
+	}
+	home := os.Getenv("HOME")
+	if home == "" {
+		return path
+	}
+	path = home + path[1:]
+	return path
+}
+
+//////////////////////////////////////////////////////
+// PIPELINE
+//////////////////////////////////////////////////////
+
+// Stdout ... Universal output, per default to Stdout
+// but it could be any other io.Writer, too
+func (p *Pipeline) Stdout() {
+	if p.Error.Err != nil {
+		return
+	}
+	io.Copy(p.Output, p.Reader)
+	// Cosmetic improvements at the output
+	lineBreak := bytes.Buffer{}
+	lineBreak.WriteString("\n")
+	io.Copy(p.Output, &lineBreak)
+}
+
+//////////////////////////////////////////////////////
+// INDEX
+//////////////////////////////////////////////////////
+
+// NewIndex ... Constructor for Index
+func NewIndex() *Index {
+	entries := make(map[string]IndexEntry)
+	return &Index{entries: entries}
+}
+
+// Upsert ... Inserts a new entry or overwrites an existing entry in the index
+func (i *Index) Upsert(key string, e IndexEntry) {
+	i.entries[key] = e
+}
+
+// Delete ... Removes an entry from the index
+func (i *Index) Delete(key string) error {
+	_, exist := i.entries[key]
+	if !exist {
+		return fmt.Errorf("index entry for key %s does not exist", key)
+	}
+	delete(*&i.entries, key)
+	return nil
+}
+
+// Resolve ... Finds the key for a reference, which is a unique prefix
+// of a key or of a checksum, case doesn't matter, memos in the trash
+// count only if no other memo matches
+func (i *Index) Resolve(ref string) (string, error) {
+	if ref == "" {
+		return "", errors.New("empty memo reference")
+	}
+	ref = strings.ToLower(ref)
+	candidates, trashed := []string{}, []string{}
+	for key, entry := range i.entries {
+		if strings.HasPrefix(strings.ToLower(key), ref) || strings.HasPrefix(entry.Checksum, ref) {
+			if entry.Deleted != "" {
+				trashed = append(trashed, key)
+				continue
+			}
+			candidates = append(candidates, key)
+		}
+	}
+	if len(candidates) == 0 {
+		candidates = trashed
+	}
+	switch len(candidates) {
+	case 0:
+		return "", fmt.Errorf("no memo found for %q", ref)
+	case 1:
+		return candidates[0], nil
+	}
+	sort.Strings(candidates)
+	return "", &AmbiguousError{Ref: ref, Candidates: candidates}
+}
+
+// Prefixes ... Calculates the shortest prefix for each key, which
+// resolves uniquely, but never shorter than minPrefix, memos in the
+// trash don't lengthen the prefixes of the others
+func (i *Index) Prefixes() map[string]string {
+	live := NewIndex()
+	for key, entry := range i.entries {
+		if entry.Deleted == "" {
+			live.Upsert(key, entry)
+		}
+	}
+	prefixes := i.prefixes()
+	for key, prefix := range live.prefixes() {
+		prefixes[key] = prefix
+	}
+	return prefixes
+}
+
+// prefixes ... Calculates the shortest unique prefix for each key among
+// all entries
+func (i *Index) prefixes() map[string]string {
+	type owned struct {
+		name string
+		key  string
+	}
+	names := []owned{}
+	for key, entry := range i.entries {
+		names = append(names, owned{strings.ToLower(key), key})
+		if entry.Checksum != "" {
+			names = append(names, owned{entry.Checksum, key})
+		}
+	}
+	sort.Slice(names, func(a, b int) bool { return names[a].name < names[b].name })
+	prefixes := make(map[string]string, len(i.entries))
+	for n, name := range names {
+		if strings.ToLower(name.key) != name.name {
+			continue
+		}
+		// The nearest names of other keys decide about the length
+		length := minPrefix
+		for m := n - 1; m >= 0; m-- {
+			if names[m].key != name.key {
+				if common := commonPrefix(names[m].name, name.name) + 1; common > length {
+					length = common
+				}
+				break
+			}
+		}
+		for m := n + 1; m < len(names); m++ {
+			if names[m].key != name.key {
+				if common := commonPrefix(names[m].name, name.name) + 1; common > length {
+					length = common
+				}
+				break
+			}
+		}
+		if length > len(name.key) {
+			length = len(name.key)
+		}
+		prefixes[name.key] = name.key[:length]
+	}
+	return prefixes
+}
+
+// SortedTags ... Lists the tags of an entry in sorted order
+func (e IndexEntry) SortedTags() []string {
+	tags := make([]string, 0, len(e.Tags))
+	for tag := range e.Tags {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// Keys ... Lists all keys of the index in sorted order
+func (i *Index) Keys() []string {
+	keys := make([]string, 0, len(i.entries))
+	for key := range i.entries {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Find ... Looks for an entry in the index by key
+func (i *Index) Find(key string) IndexEntry {
+	return i.entries[key]
+}
+
+// Store ... Stores entries into file at path
+func (i *Index) Store(path string) error {
+	lock.Lock()
+	defer lock.Unlock()
+	return i.store(path)
 }
 
 // Load ... Fills the entries from file at path
 func (i *Index) Load(path string) error {
 	lock.Lock()
 	defer lock.Unlock()
+	return i.load(path)
+}
+
+// Update ... Loads the index from file at path, changes it and stores
+// it again, all under the lock, nothing gets stored on errors
+func (i *Index) Update(path string, change func(i *Index) error) error {
+	lock.Lock()
+	defer lock.Unlock()
+	err := i.load(path)
+	if err != nil {
+		return err
+	}
+	err = change(i)
+	if err != nil {
+		return err
+	}
+	return i.store(path)
+}
+
+// store ... Writes a temporary file first and replaces the old one
+// afterwards, so readers never see half an index
+func (i *Index) store(path string) error {
+	r, err := Marshal(i.entries)
+	if err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	err = f.Chmod(0o644)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	_, err = io.Copy(f, r)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	err = f.Close()
+	if err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// load ... Reads the entries, a missing file is an empty index
+func (i *Index) load(path string) error {
 	f, err := os.Open(path)
 	if err != nil {
 		if errors.Is(err, os.ErrNotExist) {
@@ -625,11 +1475,7 @@ func (i *Index) Load(path string) error {
 		return err
 	}
 	defer f.Close()
-	err = Unmarshal(f, &i.entries)
-	if err != nil {
-		return err
-	}
-	return nil
+	return Unmarshal(f, &i.entries)
 }
 
 //////////////////////////////////////////////////////
@@ -648,6 +1494,77 @@ func (m *Memo) Read(r io.Reader) error {
 	return dec.Decode(m)
 }
 
+// entry ... creates an index entry for the memo stored in dir
+func (m *Memo) entry(dir string) IndexEntry {
+	e := IndexEntry{
+		Checksum: m.Checksum,
+		Tags:     make(map[string]bool),
+		Path:     dir,
+		Modified: m.Modified,
+	}
+	for _, tag := range m.Tags {
+		e.Tags[tag] = true
+	}
+	return e
+}
+
+// load ... reads the memo with key from dir
+func (m *Memo) load(dir, key string) error {
+	fi, err := os.Open(dir + string(os.PathSeparator) + key + ".memo")
+	if err != nil {
+		return err
+	}
+	defer fi.Close()
+	return m.Read(fi)
+}
+
+// save ... writes the memo with key into dir, a temporary file
+// replaces the old one afterwards
+func (m *Memo) save(dir, key string) error {
+	err := os.MkdirAll(dir, os.ModePerm)
+	if err != nil {
+		return err
+	}
+	fi, err := os.CreateTemp(dir, key+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(fi.Name())
+	err = fi.Chmod(0o644)
+	if err == nil {
+		err = m.Write(fi)
+	}
+	if err != nil {
+		fi.Close()
+		return err
+	}
+	err = fi.Close()
+	if err != nil {
+		return err
+	}
+	return os.Rename(fi.Name(), dir+string(os.PathSeparator)+key+".memo")
+}
+
+// Print ... writes a memo in one of the output formats, that is
+// short (memo only, default), long (all fields) or verbose (all
+// fields with labels)
+func (m *Memo) Print(w io.Writer, what, ref string) error {
+	var err error
+	switch what {
+	case "long":
+		_, err = fmt.Fprintf(w, "\n%s %s\n%s", ref, m.Modified, m.Content)
+	case "verbose":
+		_, err = fmt.Fprintf(w, "\nRef: %s\nModified: %s\nTags: %s\nMemo: %s",
+			ref,
+			m.Modified,
+			strings.Join(m.Tags, ", "),
+			m.Content)
+	default:
+		_, err = fmt.Fprintf(w, "\n%s", m.Content)
+	}
+	return err
+}
+
 // Hash ... calculates the hash sum of a memo's content
 func (m *Memo) Hash() (hash string, err error) {
 	buf := bytes.Buffer{}
@@ -675,10 +1592,92 @@ func (m *Memo) GetPath(path string) (string, error) {
 	return path, nil
 }
 
+// NewID ... creates an ID from the memo's timestamp
+func (m *Memo) NewID() (string, error) {
+	modified, err := time.ParseInLocation(timeLayout, m.Modified, time.Local)
+	if err != nil {
+		return "", err
+	}
+	return NewID(modified)
+}
+
+//////////////////////////////////////////////////////
+// ID
+//////////////////////////////////////////////////////
+
+// NewID ... creates a ULID-style ID, 48 bits of milliseconds
+// followed by 80 random bits, both encoded in Crockford's base32,
+// so IDs sort by time of creation
+func NewID(t time.Time) (string, error) {
+	random := make([]byte, 10)
+	_, err := rand.Read(random)
+	if err != nil {
+		return "", err
+	}
+	id := make([]byte, 26)
+	ms := uint64(t.UnixMilli())
+	for i := 9; i >= 0; i-- {
+		id[i] = idAlphabet[ms&31]
+		ms >>= 5
+	}
+	// 80 bits fit exactly into 16 characters of 5 bits
+	var hi, lo uint64
+	hi = uint64(random[0])<<8 | uint64(random[1])
+	for _, b := range random[2:] {
+		lo = lo<<8 | uint64(b)
+	}
+	for i := 25; i >= 10; i-- {
+		id[i] = idAlphabet[lo&31]
+		lo = lo>>5 | (hi&31)<<59
+		hi >>= 5
+	}
+	return string(id), nil
+}
+
+// isLegacyKey ... tells if a key is a content hash from the times before IDs
+func isLegacyKey(key string) bool {
+	if len(key) != sha256.Size*2 {
+		return false
+	}
+	for _, c := range key {
+		if !strings.ContainsRune("0123456789abcdef", c) {
+			return false
+		}
+	}
+	return true
+}
+
+// sortedKeys ... lists the keys of index entries in sorted order
+func sortedKeys(entries map[string]IndexEntry) []string {
+	keys := make([]string, 0, len(entries))
+	for key := range entries {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// commonPrefix ... counts the leading bytes two strings share
+func commonPrefix(a, b string) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[n] == b[n] {
+		n++
+	}
+	return n
+}
+
 //////////////////////////////////////////////////////
 // MULTIERROR
 //////////////////////////////////////////////////////
 
 func (e *MaskedError) Error() string {
-	return e.Prefix + e.Error()
+	return e.Prefix + e.Err.Error()
+}
+
+func (e *TagError) Error() string {
+	return fmt.Sprintf("invalid tag %q, %s", e.Tag, e.Reason)
+}
+
+func (e *AmbiguousError) Error() string {
+	return fmt.Sprintf("memo reference %q is ambiguous, candidates are %s", e.Ref, strings.Join(e.Candidates, ", "))
 }
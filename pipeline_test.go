@@ -2,9 +2,13 @@ package pipeline_test
 
 import (
 	"bytes"
+	"errors"
 	"io"
+	"os"
+	"path/filepath"
 	"pipeline"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 )
@@ -24,14 +28,14 @@ func TestTagIt(t *testing.T) {
 	if err != nil {
 		t.Fatalf("want no error, got %q\n", err)
 	}
-	w := pipeline.TagIt(tagged, "")
+	w := pipeline.TagIt(tagged, "testdata/index.dat")
 	if w.Error.Err != nil {
 		t.Fatalf("want no error, got %q\n", w.Error.Err)
 	}
 	want := &bytes.Buffer{}
 	w.Output = want
 	w.Stdout()
-	g := pipeline.TagIt(pure, "test")
+	g := pipeline.TagIt(pure, "testdata/index.dat", "test")
 	if g.Error.Err != nil {
 		t.Fatalf("want no error from TagIt, got %q\n", g.Error.Err)
 	}
@@ -53,7 +57,7 @@ func TestGetAll(t *testing.T) {
 		Path:     "testdata/2022/07",
 		Modified: "09.07.2022 22:26:15",
 	}
-	wa, err := pipeline.Marshal(w)
+	wa, err := pipeline.Marshal(pipeline.NewStream(w))
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -109,7 +113,7 @@ func TestStdoutShort(t *testing.T) {
 		t.Fatalf("want no error for Get, but got %q\n", i.Error.Err)
 	}
 
-	g := pipeline.Stdout(i.Reader, "short")
+	g := pipeline.Stdout(i.Reader, "short", 0, "testdata/index.dat")
 	buf := &bytes.Buffer{}
 	g.Output = buf
 	g.Stdout()
@@ -120,6 +124,364 @@ func TestStdoutShort(t *testing.T) {
 	}
 }
 
+func TestNewIDSortsByTime(t *testing.T) {
+	t.Parallel()
+	now := time.Now()
+	first, err := pipeline.NewID(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := pipeline.NewID(now.Add(time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(first) != 26 {
+		t.Errorf("want an ID of 26 characters, got %q", first)
+	}
+	if first >= second {
+		t.Errorf("want %q sorted before %q", first, second)
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	hash := "30b2efc5b47dca50dd651d291e52b237f8fe59b98f8996ac234a6ca2a0d4af80"
+	content, err := os.ReadFile("testdata/2022/07/" + hash + ".memo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(filepath.Join(dir, hash+".memo"), content, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idx := pipeline.NewIndex()
+	idx.Upsert(hash, pipeline.IndexEntry{
+		Tags:     map[string]bool{"test": true},
+		Path:     dir,
+		Modified: "09.07.2022 22:26:15",
+	})
+	idxFile := filepath.Join(dir, "index.dat")
+	err = idx.Store(idxFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	migrated, err := pipeline.Migrate(idxFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if migrated != 1 {
+		t.Fatalf("want 1 migrated entry, got %d", migrated)
+	}
+	idx = pipeline.NewIndex()
+	err = idx.Load(idxFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := idx.Keys()
+	if len(keys) != 1 || len(keys[0]) != 26 {
+		t.Fatalf("want a single entry keyed by ID, got %q", keys)
+	}
+	entry := idx.Find(keys[0])
+	if entry.Checksum != hash {
+		t.Errorf("want checksum %q, got %q", hash, entry.Checksum)
+	}
+	fi, err := os.Open(filepath.Join(dir, keys[0]+".memo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fi.Close()
+	memo := pipeline.Memo{}
+	err = memo.Read(fi)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if memo.ID != keys[0] || memo.Checksum != hash {
+		t.Errorf("want memo with ID %q and checksum %q, got %q and %q", keys[0], hash, memo.ID, memo.Checksum)
+	}
+	migrated, err = pipeline.Migrate(idxFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if migrated != 0 {
+		t.Errorf("want nothing to migrate twice, got %d", migrated)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	t.Parallel()
+	idx := pipeline.NewIndex()
+	idx.Upsert("01G7K2M0A8XYZ", pipeline.IndexEntry{Checksum: "30b2efc5"})
+	idx.Upsert("01G7K2N1B9XYZ", pipeline.IndexEntry{Checksum: "30b2aa11"})
+	tests := map[string]string{
+		"01g7k2m": "01G7K2M0A8XYZ",
+		"01G7K2N": "01G7K2N1B9XYZ",
+		"30b2ef":  "01G7K2M0A8XYZ",
+	}
+	for ref, want := range tests {
+		got, err := idx.Resolve(ref)
+		if err != nil {
+			t.Errorf("want no error for %q, got %q", ref, err)
+			continue
+		}
+		if want != got {
+			t.Errorf("want %q for %q, got %q", want, ref, got)
+		}
+	}
+	_, err := idx.Resolve("30b2")
+	ambiguous := &pipeline.AmbiguousError{}
+	if !errors.As(err, &ambiguous) {
+		t.Fatalf("want an ambiguous error, got %v", err)
+	}
+	if len(ambiguous.Candidates) != 2 {
+		t.Errorf("want 2 candidates, got %q", ambiguous.Candidates)
+	}
+	_, err = idx.Resolve("ffff")
+	if err == nil {
+		t.Error("want error for unknown reference, got none")
+	}
+}
+
+func TestResolveTrashed(t *testing.T) {
+	t.Parallel()
+	idx := pipeline.NewIndex()
+	idx.Upsert("01G7K2M0A8XYZ", pipeline.IndexEntry{Checksum: "30b2efc5"})
+	idx.Upsert("01G7K2M0B9XYZ", pipeline.IndexEntry{Checksum: "30b2aa11", Deleted: "10.07.2022 08:00:00"})
+	got, err := idx.Resolve("01g7k2m0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "01G7K2M0A8XYZ" {
+		t.Errorf("want the memo outside the trash, got %q", got)
+	}
+	got, err = idx.Resolve("01g7k2m0b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "01G7K2M0B9XYZ" {
+		t.Errorf("want the trashed memo for its own prefix, got %q", got)
+	}
+	want := map[string]string{
+		"01G7K2M0A8XYZ": "01G7K2",
+		"01G7K2M0B9XYZ": "01G7K2M0B",
+	}
+	if prefixes := idx.Prefixes(); !cmp.Equal(want, prefixes) {
+		t.Error(cmp.Diff(want, prefixes))
+	}
+}
+
+func TestPrefixes(t *testing.T) {
+	t.Parallel()
+	idx := pipeline.NewIndex()
+	idx.Upsert("01G7K2M0A8XYZ", pipeline.IndexEntry{Checksum: "30b2efc5"})
+	idx.Upsert("01G7K2M0B9XYZ", pipeline.IndexEntry{Checksum: "30b2aa11"})
+	want := map[string]string{
+		"01G7K2M0A8XYZ": "01G7K2M0A",
+		"01G7K2M0B9XYZ": "01G7K2M0B",
+	}
+	got := idx.Prefixes()
+	if !cmp.Equal(want, got) {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestShowLong(t *testing.T) {
+	t.Parallel()
+	want := "\n30b2ef 09.07.2022 22:26:15\nHello world\n\n"
+	g := pipeline.Show("30b2ef", "long", "testdata/index.dat")
+	if g.Error.Err != nil {
+		t.Fatalf("want no error for Show, but got %q\n", g.Error.Err)
+	}
+	buf := &bytes.Buffer{}
+	g.Output = buf
+	g.Stdout()
+	got := buf.String()
+	if !cmp.Equal(want, got) {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestShowUnknownRef(t *testing.T) {
+	t.Parallel()
+	p := pipeline.Show("ffffff", "short", "testdata/index.dat")
+	if p.Error.Err == nil {
+		t.Fatal("want error for unknown memo reference, but got none")
+	}
+}
+
+// tempStore ... creates a store with a single memo in a temp dir
+func tempStore(t *testing.T) (idxFile, key string) {
+	t.Helper()
+	dir := t.TempDir()
+	key = "01G7GVMW9R5Y3X8K7JCD0TQ2ZB"
+	memo := pipeline.Memo{
+		ID:       key,
+		Checksum: "30b2efc5b47dca50dd651d291e52b237f8fe59b98f8996ac234a6ca2a0d4af80",
+		Tags:     []string{"test"},
+		Modified: "09.07.2022 22:26:15",
+		Content:  "Hello world\n",
+	}
+	fi, err := os.Create(filepath.Join(dir, key+".memo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fi.Close()
+	err = memo.Write(fi)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idx := pipeline.NewIndex()
+	idx.Upsert(key, pipeline.IndexEntry{
+		Checksum: memo.Checksum,
+		Tags:     map[string]bool{"test": true},
+		Path:     dir,
+		Modified: memo.Modified,
+	})
+	idxFile = filepath.Join(dir, "index.dat")
+	err = idx.Store(idxFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return idxFile, key
+}
+
+func TestForget(t *testing.T) {
+	t.Parallel()
+	idxFile, key := tempStore(t)
+	memoFile := filepath.Join(filepath.Dir(idxFile), key+".memo")
+	p := pipeline.Forget(nil, []string{"30b2ef"}, idxFile, true, nil)
+	if p.Error.Err != nil {
+		t.Fatalf("want no error from a dry run, got %q", p.Error.Err)
+	}
+	_, err := os.Stat(memoFile)
+	if err != nil {
+		t.Fatalf("want memo file kept on a dry run, got %q", err)
+	}
+	p = pipeline.Forget(nil, []string{"30b2ef"}, idxFile, false, func([]string) bool { return false })
+	if p.Error.Err == nil {
+		t.Fatal("want error without confirmation, got none")
+	}
+	p = pipeline.Forget(nil, []string{"30b2ef"}, idxFile, false, nil)
+	if p.Error.Err != nil {
+		t.Fatalf("want no error, got %q", p.Error.Err)
+	}
+	_, err = os.Stat(memoFile)
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("want memo file moved away, got %v", err)
+	}
+	trashFile := filepath.Join(filepath.Dir(idxFile), "trash", key+".memo")
+	_, err = os.Stat(trashFile)
+	if err != nil {
+		t.Errorf("want memo file in the trash, got %q", err)
+	}
+	g := pipeline.From(pipeline.PeriodAll, idxFile)
+	if g.Error.Err != nil {
+		t.Fatalf("want no error from From, got %q", g.Error.Err)
+	}
+	entries, err := pipeline.ReadStream(g.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("want trashed memos hidden by From, got %v", entries)
+	}
+}
+
+func TestForgetRollsBack(t *testing.T) {
+	t.Parallel()
+	idxFile, key := tempStore(t)
+	// The second memo file is missing, so its move fails
+	addMemo(t, idxFile, "01G7H0000000000000000000K1", nil, "Gone\n")
+	err := os.Remove(filepath.Join(filepath.Dir(idxFile), "01G7H0000000000000000000K1.memo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := pipeline.Forget(nil, []string{key, "01G7H0000000000000000000K1"}, idxFile, false, nil)
+	if p.Error.Err == nil {
+		t.Fatal("want error from Forget, got none")
+	}
+	_, err = os.Stat(filepath.Join(filepath.Dir(idxFile), key+".memo"))
+	if err != nil {
+		t.Errorf("want the first memo moved back, got %q", err)
+	}
+	g := pipeline.Show(key, "short", idxFile)
+	if g.Error.Err != nil {
+		t.Errorf("want the first memo shown, got %q", g.Error.Err)
+	}
+}
+
+func TestShowTrashed(t *testing.T) {
+	t.Parallel()
+	idxFile, key := tempStore(t)
+	p := pipeline.Forget(nil, []string{key}, idxFile, false, nil)
+	if p.Error.Err != nil {
+		t.Fatalf("want no error from Forget, got %q", p.Error.Err)
+	}
+	g := pipeline.Show(key, "long", idxFile)
+	if g.Error.Err != nil {
+		t.Fatalf("want no error from Show, got %q", g.Error.Err)
+	}
+	buf := &bytes.Buffer{}
+	g.Output = buf
+	g.Stdout()
+	if want := "\n01G7GV (trash) 09.07.2022 22:26:15\nHello world\n\n"; buf.String() != want {
+		t.Errorf("want %q, got %q", want, buf.String())
+	}
+}
+
+func TestRestore(t *testing.T) {
+	t.Parallel()
+	idxFile, key := tempStore(t)
+	p := pipeline.Forget(nil, []string{key}, idxFile, false, nil)
+	if p.Error.Err != nil {
+		t.Fatalf("want no error from Forget, got %q", p.Error.Err)
+	}
+	p = pipeline.Restore([]string{"30b2ef"}, idxFile)
+	if p.Error.Err != nil {
+		t.Fatalf("want no error from Restore, got %q", p.Error.Err)
+	}
+	g := pipeline.Show(key, "short", idxFile)
+	if g.Error.Err != nil {
+		t.Fatalf("want restored memo shown, got %q", g.Error.Err)
+	}
+	idx := pipeline.NewIndex()
+	err := idx.Load(idxFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := filepath.Join(filepath.Dir(idxFile), "2022", "07")
+	if got := idx.Find(key).Path; want != got {
+		t.Errorf("want restored memo at %q, got %q", want, got)
+	}
+}
+
+func TestEmptyTrash(t *testing.T) {
+	t.Parallel()
+	idxFile, key := tempStore(t)
+	p := pipeline.Forget(nil, []string{key}, idxFile, false, nil)
+	if p.Error.Err != nil {
+		t.Fatalf("want no error from Forget, got %q", p.Error.Err)
+	}
+	purged, err := pipeline.EmptyTrash(24*time.Hour, idxFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if purged != 0 {
+		t.Errorf("want fresh trash kept, got %d purged", purged)
+	}
+	purged, err = pipeline.EmptyTrash(0, idxFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if purged != 1 {
+		t.Errorf("want 1 purged memo, got %d", purged)
+	}
+	_, err = os.Stat(filepath.Join(filepath.Dir(idxFile), "trash", key+".memo"))
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("want memo file purged, got %v", err)
+	}
+}
+
 // Found no negative scenario to test the errors from pipeline.TagIt()
 
 /*
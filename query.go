@@ -0,0 +1,310 @@
+package pipeline
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// QueryExpr ... tags, a time frame and text a memo has to match, zero
+// times don't limit the frame
+type QueryExpr struct {
+	Tags    []string
+	NotTags []string
+	Since   time.Time
+	Until   time.Time
+	Text    SearchQuery
+}
+
+//////////////////////////////////////////////////////////
+// Query ... reads the index and keeps the entries,
+// which match all parts of a query expression, each part
+// is parsed on its own, text hits come ranked
+// used by query
+//////////////////////////////////////////////////////////
+func Query(filePath string, parts ...string) *Pipeline {
+	// Help wanted?
+	if len(parts) > 0 && parts[0] == "help" {
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "QUERY is part of memo's famous toolbox")
+		fmt.Fprintln(os.Stderr, "--------------------------------------")
+		fmt.Fprintln(os.Stderr, "Usage: query '<expression>' | further tools")
+		fmt.Fprintln(os.Stderr, "       query @<name> ['<expression>'] | further tools")
+		fmt.Fprintln(os.Stderr, "       query --save <name> [--description <text>] '<expression>'")
+		fmt.Fprintln(os.Stderr, "       query --list")
+		fmt.Fprintln(os.Stderr, "       query --remove <name>")
+		fmt.Fprintln(os.Stderr, "       query help")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "It reads the index file for memos like from and keeps the entries,")
+		fmt.Fprintln(os.Stderr, "which match all parts of the expression, so one query does the job of")
+		fmt.Fprintln(os.Stderr, "from, tagged and search ...")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "  query 'tag:work -tag:done since:2022-06-01 \"rollout plan\"'")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Following parts are supported by now ...")
+		fmt.Fprintln(os.Stderr, "  ✓ tag:<tag> ... tagged with the tag or one of its descendants")
+		fmt.Fprintln(os.Stderr, "  ✓ -tag:<tag> ... not tagged with it")
+		fmt.Fprintln(os.Stderr, "  ✓ since:<when> ... modified at or after")
+		fmt.Fprintln(os.Stderr, "  ✓ until:<when> ... modified before the end of")
+		fmt.Fprintln(os.Stderr, "  ✓ in:<period> ... modified within")
+		fmt.Fprintln(os.Stderr, "  ✓ word ... containing the word, regardless of its case")
+		fmt.Fprintln(os.Stderr, "  ✓ \"some words\" ... containing the words next to each other")
+		fmt.Fprintln(os.Stderr, "  ✓ -word, -\"some words\" ... not containing them")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "<when> is a date like 2022-06-01 or 01.06.2022, an age like 30d, 2w or")
+		fmt.Fprintln(os.Stderr, "12h back from now, or a period like today, yesterday, thisweek, lastweek,")
+		fmt.Fprintln(os.Stderr, "last2weeks, thismonth, lastmonth, last2months, thisyear, lastyear and")
+		fmt.Fprintln(os.Stderr, "last2years. Weeks start on Monday, the last two weeks, months and years")
+		fmt.Fprintln(os.Stderr, "include the current one. Memos in the trash are left out.")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Entries come in the order of their IDs, with words they come ranked")
+		fmt.Fprintln(os.Stderr, "like by search.")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Recurring queries get saved by name in the config of the store. @name")
+		fmt.Fprintln(os.Stderr, "runs them again, further parts narrow them down. from @name does the")
+		fmt.Fprintln(os.Stderr, "same. Saved searches must not refer to other saved searches.")
+		fmt.Fprintln(os.Stderr)
+		toolbox()
+		os.Exit(0)
+	}
+	if filePath == "" {
+		filePath = TakeMeHome(basePath + string(os.PathSeparator) + indexFile)
+	}
+	cfg, err := LoadConfig(filePath)
+	if err != nil {
+		return &Pipeline{
+			Error: MaskedError{
+				Prefix: "✘ error ... ",
+				Err:    err,
+			},
+		}
+	}
+	// Saved searches start with their name
+	if len(parts) > 0 && strings.HasPrefix(parts[0], "@") {
+		name, rest, _ := strings.Cut(parts[0][1:], " ")
+		saved, exist := cfg.Searches[name]
+		if !exist {
+			return &Pipeline{
+				Error: MaskedError{
+					Prefix: "✘ error ... ",
+					Err:    fmt.Errorf("search @%s does not exist", name),
+				},
+			}
+		}
+		parts = append([]string{saved.Query, rest}, parts[1:]...)
+	}
+	q := QueryExpr{}
+	now := time.Now()
+	for _, part := range parts {
+		parsed, err := ParseQuery(part, cfg, now)
+		if err != nil {
+			return &Pipeline{
+				Error: MaskedError{
+					Prefix: "✘ error ... ",
+					Err:    err,
+				},
+			}
+		}
+		q.narrow(parsed)
+	}
+	idx := NewIndex()
+	err = idx.Load(filePath)
+	if err != nil {
+		return &Pipeline{
+			Error: MaskedError{
+				Prefix: "✘ error ... ",
+				Err:    err,
+			},
+		}
+	}
+
+	found := Stream{}
+	for _, key := range sortedKeys(idx.entries) {
+		entry := idx.entries[key]
+		if entry.Deleted != "" {
+			continue
+		}
+		matched, err := q.matchEntry(entry, cfg)
+		if err != nil {
+			return &Pipeline{
+				Error: MaskedError{
+					Prefix: "✘ error ... ",
+					Err:    err,
+				},
+			}
+		}
+		if matched {
+			found = append(found, StreamEntry{Key: key, IndexEntry: entry})
+		}
+	}
+	if len(q.Text.Include) > 0 || len(q.Text.Exclude) > 0 {
+		found, err = searchStream(q.Text, found, filePath)
+		if err != nil {
+			return &Pipeline{
+				Error: MaskedError{
+					Prefix: "✘ error ... ",
+					Err:    err,
+				},
+			}
+		}
+	}
+
+	filtered, err := Marshal(found)
+	if err != nil {
+		return &Pipeline{
+			Error: MaskedError{
+				Prefix: "✘ error ... ",
+				Err:    err,
+			},
+		}
+	}
+
+	return &Pipeline{
+		Reader: filtered,
+	}
+}
+
+//////////////////////////////////////////////////////////
+// ParseQuery ... parses an expression of tag:, since:,
+// until: and in: parts, words and phrases, dates and
+// periods count from now
+//////////////////////////////////////////////////////////
+func ParseQuery(expr string, cfg Config, now time.Time) (QueryExpr, error) {
+	tokens, err := splitSearch(expr)
+	if err != nil {
+		return QueryExpr{}, err
+	}
+	q := QueryExpr{}
+	for _, token := range tokens {
+		key, value, found := strings.Cut(token.text, ":")
+		key = strings.ToLower(key)
+		if token.quoted || !found || (key != "tag" && key != "since" && key != "until" && key != "in") {
+			err := q.Text.add(expr, token)
+			if err != nil {
+				return QueryExpr{}, err
+			}
+			continue
+		}
+		if value == "" {
+			return QueryExpr{}, &ExprError{Expr: expr, Pos: token.pos, Msg: fmt.Sprintf("%s: needs a value", key)}
+		}
+		if key == "tag" {
+			tag, err := NormalizeTag(value, cfg)
+			if err != nil {
+				return QueryExpr{}, &ExprError{Expr: expr, Pos: token.pos, Msg: err.Error()}
+			}
+			if token.exclude {
+				q.NotTags = append(q.NotTags, tag)
+			} else {
+				q.Tags = append(q.Tags, tag)
+			}
+			continue
+		}
+		if token.exclude {
+			return QueryExpr{}, &ExprError{Expr: expr, Pos: token.pos, Msg: fmt.Sprintf("%s: can't be excluded", key)}
+		}
+		start, end, err := timeFrame(value, now)
+		if err != nil {
+			return QueryExpr{}, &ExprError{Expr: expr, Pos: token.pos, Msg: err.Error()}
+		}
+		switch key {
+		case "since":
+			q.Since = start
+		case "until":
+			q.Until = end
+		default:
+			q.Since, q.Until = start, end
+		}
+	}
+	return q, nil
+}
+
+// narrow ... adds the parts of another query, both must match, so the
+// later start and the earlier end of their time frames count
+func (q *QueryExpr) narrow(other QueryExpr) {
+	q.Tags = append(q.Tags, other.Tags...)
+	q.NotTags = append(q.NotTags, other.NotTags...)
+	if q.Since.IsZero() || other.Since.After(q.Since) {
+		q.Since = other.Since
+	}
+	if q.Until.IsZero() || (!other.Until.IsZero() && other.Until.Before(q.Until)) {
+		q.Until = other.Until
+	}
+	q.Text.Include = append(q.Text.Include, other.Text.Include...)
+	q.Text.Exclude = append(q.Text.Exclude, other.Text.Exclude...)
+}
+
+// matchEntry ... tells whether tags and modification time of an entry
+// match the query, the text gets searched elsewhere
+func (q QueryExpr) matchEntry(e IndexEntry, cfg Config) (bool, error) {
+	tags := e.normalizedTags(cfg)
+	for _, tag := range q.Tags {
+		if !hasTag(tags, tag, false) {
+			return false, nil
+		}
+	}
+	for _, tag := range q.NotTags {
+		if hasTag(tags, tag, false) {
+			return false, nil
+		}
+	}
+	if q.Since.IsZero() && q.Until.IsZero() {
+		return true, nil
+	}
+	modified, err := time.ParseInLocation(timeLayout, e.Modified, time.Local)
+	if err != nil {
+		return false, err
+	}
+	if !q.Since.IsZero() && modified.Before(q.Since) {
+		return false, nil
+	}
+	if !q.Until.IsZero() && !modified.Before(q.Until) {
+		return false, nil
+	}
+	return true, nil
+}
+
+// timeFrame ... the start and the end of a date, a period or the point
+// in time an age ago
+func timeFrame(when string, now time.Time) (time.Time, time.Time, error) {
+	for _, layout := range []string{"2006-01-02", "02.01.2006"} {
+		day, err := time.ParseInLocation(layout, when, now.Location())
+		if err == nil {
+			return day, day.AddDate(0, 0, 1), nil
+		}
+	}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	monday := today.AddDate(0, 0, -(int(today.Weekday())+6)%7)
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	year := time.Date(now.Year(), 1, 1, 0, 0, 0, 0, now.Location())
+	switch Period(strings.ToLower(when)) {
+	case PeriodToday:
+		return today, today.AddDate(0, 0, 1), nil
+	case PeriodYesterday:
+		return today.AddDate(0, 0, -1), today, nil
+	case PeriodThisWeek:
+		return monday, monday.AddDate(0, 0, 7), nil
+	case PeriodLastWeek:
+		return monday.AddDate(0, 0, -7), monday, nil
+	case PeriodLastTwoWeeks:
+		return monday.AddDate(0, 0, -7), monday.AddDate(0, 0, 7), nil
+	case PeriodThisMonth:
+		return month, month.AddDate(0, 1, 0), nil
+	case PeriodLastMonth:
+		return month.AddDate(0, -1, 0), month, nil
+	case PeriodLastTwoMonths:
+		return month.AddDate(0, -1, 0), month.AddDate(0, 1, 0), nil
+	case PeriodThisYear:
+		return year, year.AddDate(1, 0, 0), nil
+	case PeriodLastYear:
+		return year.AddDate(-1, 0, 0), year, nil
+	case PeriodLastTwoYears:
+		return year.AddDate(-1, 0, 0), year.AddDate(1, 0, 0), nil
+	}
+	age, err := ParseAge(when)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("unknown date or period %q", when)
+	}
+	return now.Add(-age), now.Add(-age), nil
+}
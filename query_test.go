@@ -0,0 +1,134 @@
+package pipeline_test
+
+import (
+	"errors"
+	"pipeline"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseQuery(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2022, 7, 13, 15, 4, 5, 0, time.Local)
+	q, err := pipeline.ParseQuery(`Tag:Work -tag:done since:2022-06-01 until:yesterday "rollout plan" -draft`, pipeline.Config{}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := pipeline.QueryExpr{
+		Tags:    []string{"work"},
+		NotTags: []string{"done"},
+		Since:   time.Date(2022, 6, 1, 0, 0, 0, 0, time.Local),
+		Until:   time.Date(2022, 7, 13, 0, 0, 0, 0, time.Local),
+		Text: pipeline.SearchQuery{
+			Include: [][]string{{"rollout", "plan"}},
+			Exclude: [][]string{{"draft"}},
+		},
+	}
+	if !cmp.Equal(want, q) {
+		t.Error(cmp.Diff(want, q))
+	}
+	q, err = pipeline.ParseQuery(`in:lastweek`, pipeline.Config{}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !q.Since.Equal(time.Date(2022, 7, 4, 0, 0, 0, 0, time.Local)) || !q.Until.Equal(time.Date(2022, 7, 11, 0, 0, 0, 0, time.Local)) {
+		t.Errorf("want last week from Monday to Monday, got %v to %v", q.Since, q.Until)
+	}
+	for _, expr := range []string{`tag:`, `-since:today`, `since:someday`, `tag:a,b`, `"rollout`} {
+		_, err := pipeline.ParseQuery(expr, pipeline.Config{}, now)
+		var exprErr *pipeline.ExprError
+		if !errors.As(err, &exprErr) {
+			t.Errorf("want expression error for %q, got %v", expr, err)
+		}
+	}
+}
+
+func TestQuery(t *testing.T) {
+	t.Parallel()
+	idxFile, key := tempStore(t)
+	addMemo(t, idxFile, "01G7H0000000000000000000G1", []string{"work"}, "The rollout plan\n")
+	addMemo(t, idxFile, "01G7H0000000000000000000G2", []string{"work", "done"}, "The rollout plan, done\n")
+	tests := []struct {
+		parts []string
+		want  []string
+	}{
+		{[]string{}, []string{key, "01G7H0000000000000000000G1", "01G7H0000000000000000000G2"}},
+		{[]string{`tag:work -tag:done`}, []string{"01G7H0000000000000000000G1"}},
+		{[]string{`tag:work "rollout plan" -done`}, []string{"01G7H0000000000000000000G1"}},
+		{[]string{`since:10.07.2022`}, []string{"01G7H0000000000000000000G1", "01G7H0000000000000000000G2"}},
+		{[]string{`until:2022-07-09 hello`}, []string{key}},
+		{[]string{`in:2022-07-11`}, []string{}},
+		{[]string{`tag:work`, `"rollout plan"`, `-done`}, []string{"01G7H0000000000000000000G1"}},
+		{[]string{`since:2022-07-01`, `until:2022-07-09`}, []string{key}},
+	}
+	for _, test := range tests {
+		p := pipeline.Query(idxFile, test.parts...)
+		if p.Error.Err != nil {
+			t.Fatalf("want no error from Query, got %q", p.Error.Err)
+		}
+		found, err := pipeline.ReadStream(p.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := []string{}
+		for _, entry := range found {
+			got = append(got, entry.Key)
+		}
+		if !cmp.Equal(test.want, got) {
+			t.Errorf("%q: %s", test.parts, cmp.Diff(test.want, got))
+		}
+	}
+}
+
+func TestSavedSearch(t *testing.T) {
+	t.Parallel()
+	idxFile, _ := tempStore(t)
+	addMemo(t, idxFile, "01G7H0000000000000000000H1", []string{"incident"}, "Database down\n")
+	addMemo(t, idxFile, "01G7H0000000000000000000H2", []string{"incident", "done"}, "Disk full\n")
+	err := pipeline.SaveSearch("incidents", "tag:incident", "the incident log", idxFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := pipeline.LoadConfig(idxFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]pipeline.SavedSearch{"incidents": {Query: "tag:incident", Description: "the incident log"}}
+	if !cmp.Equal(want, cfg.Searches) {
+		t.Error(cmp.Diff(want, cfg.Searches))
+	}
+	for _, p := range []*pipeline.Pipeline{
+		pipeline.Query(idxFile, "@incidents", "-tag:done"),
+		pipeline.From("@incidents -tag:done", idxFile),
+	} {
+		if p.Error.Err != nil {
+			t.Fatalf("want no error for @incidents, got %q", p.Error.Err)
+		}
+		found, err := pipeline.ReadStream(p.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(found) != 1 || found[0].Key != "01G7H0000000000000000000H1" {
+			t.Errorf("want the open incident only, got %v", found)
+		}
+	}
+	err = pipeline.RemoveSearch("incidents", idxFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := pipeline.Query(idxFile, "@incidents"); p.Error.Err == nil {
+		t.Error("want error for a removed search, got none")
+	}
+	for _, name := range []string{"", "stand up", "@standup"} {
+		if err := pipeline.SaveSearch(name, "tag:work", "", idxFile); err == nil {
+			t.Errorf("want error for name %q, got none", name)
+		}
+	}
+	for _, expr := range []string{`"rollout`, "", "  ", "tag:work @incidents"} {
+		if err := pipeline.SaveSearch("broken", expr, "", idxFile); err == nil {
+			t.Errorf("want error for expression %q, got none", expr)
+		}
+	}
+}
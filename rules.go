@@ -0,0 +1,243 @@
+package pipeline
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const rulesFile = "rules.txt"
+
+type (
+	// Rule ... tags a memo, if its content matches the pattern
+	Rule struct {
+		Source  string // the rule as written in the rules file
+		Pattern *regexp.Regexp
+		Tags    []string
+	}
+)
+
+// Keywords are plain words, everything else is a regular expression
+var keywords = regexp.MustCompile(`^[\p{L}\p{N}_\- ,]+$`)
+
+//////////////////////////////////////////////////////////
+// LoadRules ... reads the rules of the store, where the
+// index at filePath lives, a missing file means no rules
+//////////////////////////////////////////////////////////
+func LoadRules(filePath string) ([]Rule, error) {
+	if filePath == "" {
+		filePath = TakeMeHome(basePath + string(os.PathSeparator) + indexFile)
+	}
+	fi, err := os.Open(filepath.Join(filepath.Dir(filePath), rulesFile))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer fi.Close()
+	return ParseRules(fi)
+}
+
+//////////////////////////////////////////////////////////
+// ParseRules ... reads rules line by line, a rule maps a
+// regular expression or a comma separated list of keywords
+// to one or more tags, like "(?i)postgres|psql -> db" or
+// "kubernetes, helm -> k8s, ops", keywords match whole
+// words regardless of their case, empty lines and lines
+// starting with # are left out
+//////////////////////////////////////////////////////////
+func ParseRules(rd io.Reader) ([]Rule, error) {
+	rules := []Rule{}
+	scanner := bufio.NewScanner(rd)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		i := strings.LastIndex(line, "->")
+		if i < 0 {
+			return nil, fmt.Errorf("rule in line %d misses the ->", n)
+		}
+		pattern := strings.TrimSpace(line[:i])
+		tags := []string{}
+		for _, tag := range strings.Split(line[i+2:], ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				tags = append(tags, tag)
+			}
+		}
+		if pattern == "" || len(tags) == 0 {
+			return nil, fmt.Errorf("rule in line %d needs a pattern and a tag", n)
+		}
+		if keywords.MatchString(pattern) {
+			words := []string{}
+			for _, word := range strings.Split(pattern, ",") {
+				if word = strings.TrimSpace(word); word != "" {
+					words = append(words, regexp.QuoteMeta(word))
+				}
+			}
+			pattern = `(?i)\b(` + strings.Join(words, "|") + `)\b`
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("rule in line %d: %w", n, err)
+		}
+		rules = append(rules, Rule{Source: line, Pattern: re, Tags: tags})
+	}
+	return rules, scanner.Err()
+}
+
+//////////////////////////////////////////////////////////
+// ApplyRules ... adds the tags of all matching rules to
+// the memo, returns the rules, which added any tag
+//////////////////////////////////////////////////////////
+func (m *Memo) ApplyRules(rules []Rule, cfg Config) ([]Rule, error) {
+	hits := []Rule{}
+	tags := m.Tags
+	known := make(map[string]bool)
+	for _, tag := range m.Tags {
+		known[tag] = true
+		if normalized, err := NormalizeTag(tag, cfg); err == nil {
+			known[normalized] = true
+		}
+	}
+	for _, rule := range rules {
+		if !rule.Pattern.MatchString(m.Content) {
+			continue
+		}
+		added, err := NormalizeTags(rule.Tags, cfg)
+		if err != nil {
+			return nil, err
+		}
+		hit := false
+		for _, tag := range added {
+			if !known[tag] {
+				known[tag] = true
+				hit = true
+			}
+		}
+		if hit {
+			hits = append(hits, rule)
+			tags = append(tags, added...)
+		}
+	}
+	if len(hits) == 0 {
+		return hits, nil
+	}
+	tags, err := NormalizeTags(tags, cfg)
+	if err != nil {
+		return nil, err
+	}
+	m.Tags = tags
+	return hits, nil
+}
+
+//////////////////////////////////////////////////////////
+// AutoTag ... runs the rules over stored memos, which come
+// by an index stream, and updates their tags
+// used by autotag
+//////////////////////////////////////////////////////////
+func AutoTag(rd io.Reader, what, filePath string) *Pipeline {
+	// Help wanted?
+	if what == "help" {
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "AUTOTAG is part of memo's famous toolbox")
+		fmt.Fprintln(os.Stderr, "----------------------------------------")
+		fmt.Fprintln(os.Stderr, "Usage: further tools | autotag | further tools")
+		fmt.Fprintln(os.Stderr, "       autotag help")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "It takes a list of index entries for memos from the pipe and tags")
+		fmt.Fprintln(os.Stderr, "them by the rules in rules.txt of the store. keep does the same for")
+		fmt.Fprintln(os.Stderr, "new memos. Each line maps a regular expression or a list of keywords")
+		fmt.Fprintln(os.Stderr, "to tags ...")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "  (?i)postgres|psql -> db")
+		fmt.Fprintln(os.Stderr, "  kubernetes, helm -> k8s, ops")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Keywords match whole words regardless of their case. The updated")
+		fmt.Fprintln(os.Stderr, "entries get piped to the next tool in the chain.")
+		fmt.Fprintln(os.Stderr)
+		toolbox()
+		os.Exit(0)
+	}
+	if filePath == "" {
+		filePath = TakeMeHome(basePath + string(os.PathSeparator) + indexFile)
+	}
+	rules, err := LoadRules(filePath)
+	if err != nil {
+		return &Pipeline{
+			Error: MaskedError{
+				Prefix: "✘ error ... ",
+				Err:    err,
+			},
+		}
+	}
+	cfg, err := LoadConfig(filePath)
+	if err != nil {
+		return &Pipeline{
+			Error: MaskedError{
+				Prefix: "✘ error ... ",
+				Err:    err,
+			},
+		}
+	}
+	entries, err := ReadStream(rd)
+	if err != nil {
+		return &Pipeline{
+			Error: MaskedError{
+				Prefix: "✘ error ... ",
+				Err:    err,
+			},
+		}
+	}
+	keys := []string{}
+	for _, piped := range entries {
+		keys = append(keys, piped.Key)
+	}
+	hits := make(map[string][]Rule)
+	idx, _, err := rewriteMemos(filePath, keys, nil, func(key string, memo *Memo) (bool, error) {
+		ruled, err := memo.ApplyRules(rules, cfg)
+		if err != nil {
+			return false, err
+		}
+		hits[key] = ruled
+		return len(ruled) > 0, nil
+	})
+	if err != nil {
+		return &Pipeline{
+			Error: MaskedError{
+				Prefix: "✘ error ... ",
+				Err:    err,
+			},
+		}
+	}
+	tagged := Stream{}
+	for _, piped := range entries {
+		reportRules(piped.Key, hits[piped.Key])
+		tagged = append(tagged, StreamEntry{Key: piped.Key, Score: piped.Score, IndexEntry: idx.Find(piped.Key)})
+	}
+	r, err := Marshal(tagged)
+	if err != nil {
+		return &Pipeline{
+			Error: MaskedError{
+				Prefix: "✘ error ... ",
+				Err:    err,
+			},
+		}
+	}
+	return &Pipeline{
+		Reader: r,
+	}
+}
+
+// reportRules ... tells about rule hits in the usual status style
+func reportRules(key string, hits []Rule) {
+	for _, rule := range hits {
+		fmt.Fprintf(os.Stderr, "✓ rule ... %s tagged %s by %s\n", key, strings.Join(rule.Tags, ", "), rule.Source)
+	}
+}
@@ -0,0 +1,95 @@
+package pipeline_test
+
+import (
+	"os"
+	"path/filepath"
+	"pipeline"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const rules = `# tags for the database team
+(?i)postgres|psql -> db
+kubernetes, helm -> k8s, ops
+
+world -> greeting
+`
+
+func TestApplyRules(t *testing.T) {
+	t.Parallel()
+	parsed, err := pipeline.ParseRules(strings.NewReader(rules))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parsed) != 3 {
+		t.Fatalf("want 3 rules, got %d", len(parsed))
+	}
+	memo := pipeline.Memo{
+		Tags:    []string{"work"},
+		Content: "Upgrade of PSQL within the Helm chart, no helmet needed\n",
+	}
+	hits, err := memo.ApplyRules(parsed, pipeline.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hits) != 2 {
+		t.Errorf("want 2 hits, got %d", len(hits))
+	}
+	want := []string{"db", "k8s", "ops", "work"}
+	if !cmp.Equal(want, memo.Tags) {
+		t.Error(cmp.Diff(want, memo.Tags))
+	}
+	hits, err = memo.ApplyRules(parsed, pipeline.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hits) != 0 {
+		t.Errorf("want no hits for tags already there, got %d", len(hits))
+	}
+	memo = pipeline.Memo{Content: "helmet only\n"}
+	hits, err = memo.ApplyRules(parsed, pipeline.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hits) != 0 {
+		t.Errorf("want keywords to match whole words only, got %d hits", len(hits))
+	}
+}
+
+func TestParseRulesInvalid(t *testing.T) {
+	t.Parallel()
+	for _, rules := range []string{"no arrow", "-> db", "postgres ->", "(?i)post[gres -> db"} {
+		_, err := pipeline.ParseRules(strings.NewReader(rules))
+		if err == nil {
+			t.Errorf("want error for %q, got none", rules)
+		}
+	}
+}
+
+func TestAutoTag(t *testing.T) {
+	t.Parallel()
+	idxFile, key := tempStore(t)
+	err := os.WriteFile(filepath.Join(filepath.Dir(idxFile), "rules.txt"), []byte(rules), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := pipeline.From(pipeline.PeriodAll, idxFile)
+	if f.Error.Err != nil {
+		t.Fatalf("want no error from From, got %q", f.Error.Err)
+	}
+	p := pipeline.AutoTag(f.Reader, "", idxFile)
+	if p.Error.Err != nil {
+		t.Fatalf("want no error from AutoTag, got %q", p.Error.Err)
+	}
+	idx := pipeline.NewIndex()
+	err = idx.Load(idxFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]bool{"greeting": true, "test": true}
+	if !cmp.Equal(want, idx.Find(key).Tags) {
+		t.Error(cmp.Diff(want, idx.Find(key).Tags))
+	}
+}
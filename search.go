@@ -0,0 +1,206 @@
+package pipeline
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// SearchQuery ... words and phrases, a memo has to contain all of
+// Include and none of Exclude, a single word is a phrase of length 1
+type SearchQuery struct {
+	Include [][]string
+	Exclude [][]string
+}
+
+//////////////////////////////////////////////////////////
+// Search ... reduces an index to entries, whose memos
+// contain the words and phrases searched for
+// used by search
+//////////////////////////////////////////////////////////
+func Search(rd io.Reader, filePath string, query ...string) *Pipeline {
+	// Help wanted?
+	if len(query) > 0 && query[0] == "help" {
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "SEARCH is part of memo's famous toolbox")
+		fmt.Fprintln(os.Stderr, "---------------------------------------")
+		fmt.Fprintln(os.Stderr, "Usage: further tools | search <term> [<term2> <termn>] | further tools")
+		fmt.Fprintln(os.Stderr, "       search help")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "It takes a list of index entries for memos from the pipe and looks")
+		fmt.Fprintln(os.Stderr, "into their content. Only these entries, whose memos contain all terms,")
+		fmt.Fprintln(os.Stderr, "will survive and get piped to the next tool in the chain. Case")
+		fmt.Fprintln(os.Stderr, "doesn't matter ...")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "  search rollout '\"release plan\"' -draft")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Quoted phrases match words next to each other, terms with a leading")
+		fmt.Fprintln(os.Stderr, "- must not be contained. The answers come from the terms file of the")
+		fmt.Fprintln(os.Stderr, "store, memos missing there get read. reindex rebuilds the terms file.")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "The entries get scored by BM25 and piped in the order of their score,")
+		fmt.Fprintln(os.Stderr, "most relevant first.")
+		fmt.Fprintln(os.Stderr)
+		toolbox()
+		os.Exit(0)
+	}
+	q, err := ParseSearch(strings.Join(query, " "))
+	if err != nil {
+		return &Pipeline{
+			Error: MaskedError{
+				Prefix: "✘ error ... ",
+				Err:    err,
+			},
+		}
+	}
+	entries, err := ReadStream(rd)
+	if err != nil {
+		return &Pipeline{
+			Error: MaskedError{
+				Prefix: "✘ error ... ",
+				Err:    err,
+			},
+		}
+	}
+
+	found, err := searchStream(q, entries, filePath)
+	if err != nil {
+		return &Pipeline{
+			Error: MaskedError{
+				Prefix: "✘ error ... ",
+				Err:    err,
+			},
+		}
+	}
+
+	filtered, err := Marshal(found)
+	if err != nil {
+		return &Pipeline{
+			Error: MaskedError{
+				Prefix: "✘ error ... ",
+				Err:    err,
+			},
+		}
+	}
+
+	return &Pipeline{
+		Reader: filtered,
+	}
+}
+
+// searchStream ... keeps the entries, whose memos satisfy the query,
+// ranked by BM25, the terms file of the store answers and memos missing
+// there get read
+func searchStream(q SearchQuery, entries Stream, filePath string) (Stream, error) {
+	if filePath == "" {
+		filePath = TakeMeHome(basePath + string(os.PathSeparator) + indexFile)
+	}
+	t := NewTermIndex()
+	err := t.Load(termsPath(filePath))
+	if err != nil {
+		return nil, err
+	}
+	found := Stream{}
+	for _, entry := range entries {
+		// Memos missing in the terms file count for this search only
+		if !t.Has(entry.Key) {
+			memo := Memo{}
+			err := memo.load(entry.Path, entry.Key)
+			if err != nil {
+				return nil, err
+			}
+			t.Add(entry.Key, memo.Content)
+		}
+		if t.Match(q, entry.Key) {
+			found = append(found, entry)
+		}
+	}
+	t.Rank(q, found)
+	return found, nil
+}
+
+//////////////////////////////////////////////////////////
+// ParseSearch ... parses words, "quoted phrases" and
+// -exclusions of both into a query
+//////////////////////////////////////////////////////////
+func ParseSearch(query string) (SearchQuery, error) {
+	tokens, err := splitSearch(query)
+	if err != nil {
+		return SearchQuery{}, err
+	}
+	q := SearchQuery{}
+	for _, token := range tokens {
+		err := q.add(query, token)
+		if err != nil {
+			return SearchQuery{}, err
+		}
+	}
+	if len(q.Include) == 0 && len(q.Exclude) == 0 {
+		return SearchQuery{}, &ExprError{Expr: query, Pos: 1, Msg: "query is empty"}
+	}
+	return q, nil
+}
+
+// searchToken ... a word or a phrase of a query, positions count runes
+// starting with 1
+type searchToken struct {
+	text    string
+	exclude bool
+	quoted  bool
+	pos     int
+}
+
+// splitSearch ... splits a query at blanks, which aren't quoted, quotes
+// get removed and a leading - marks an exclusion
+func splitSearch(query string) ([]searchToken, error) {
+	tokens := []searchToken{}
+	runes := []rune(query)
+	for n := 0; n < len(runes); {
+		if unicode.IsSpace(runes[n]) {
+			n++
+			continue
+		}
+		token := searchToken{pos: n + 1}
+		if runes[n] == '-' {
+			token.exclude = true
+			n++
+		}
+		token.quoted = n < len(runes) && runes[n] == '"'
+		text := []rune{}
+		for n < len(runes) && !unicode.IsSpace(runes[n]) {
+			if runes[n] != '"' {
+				text = append(text, runes[n])
+				n++
+				continue
+			}
+			end := n + 1
+			for end < len(runes) && runes[end] != '"' {
+				end++
+			}
+			if end == len(runes) {
+				return nil, &ExprError{Expr: query, Pos: n + 1, Msg: "unterminated phrase"}
+			}
+			text = append(text, runes[n+1:end]...)
+			n = end + 1
+		}
+		token.text = string(text)
+		tokens = append(tokens, token)
+	}
+	return tokens, nil
+}
+
+// add ... puts the words of a token into the query
+func (q *SearchQuery) add(query string, token searchToken) error {
+	phrase := terms(token.text)
+	if len(phrase) == 0 {
+		return &ExprError{Expr: query, Pos: token.pos, Msg: "nothing to search for"}
+	}
+	if token.exclude {
+		q.Exclude = append(q.Exclude, phrase)
+	} else {
+		q.Include = append(q.Include, phrase)
+	}
+	return nil
+}
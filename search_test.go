@@ -0,0 +1,122 @@
+package pipeline_test
+
+import (
+	"bytes"
+	"errors"
+	"pipeline"
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseSearch(t *testing.T) {
+	t.Parallel()
+	q, err := pipeline.ParseSearch(`Rollout "Release  plan" -draft -"old stuff"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := pipeline.SearchQuery{
+		Include: [][]string{{"rollout"}, {"release", "plan"}},
+		Exclude: [][]string{{"draft"}, {"old", "stuff"}},
+	}
+	if !cmp.Equal(want, q) {
+		t.Error(cmp.Diff(want, q))
+	}
+	_, err = pipeline.ParseSearch(`rollout "release plan`)
+	var exprErr *pipeline.ExprError
+	if !errors.As(err, &exprErr) || exprErr.Pos != 9 {
+		t.Errorf("want error at position 9, got %v", err)
+	}
+	for _, query := range []string{"", "  ", "-", `""`} {
+		_, err := pipeline.ParseSearch(query)
+		if err == nil {
+			t.Errorf("want error for %q, got none", query)
+		}
+	}
+}
+
+func TestSearch(t *testing.T) {
+	t.Parallel()
+	idxFile, key := tempStore(t)
+	addMemo(t, idxFile, "01G7H0000000000000000000B1", []string{"work"}, "The release plan for the rollout\n")
+	addMemo(t, idxFile, "01G7H0000000000000000000B2", []string{"work"}, "Plan the release, draft only\n")
+	tests := []struct {
+		query []string
+		want  []string
+	}{
+		{[]string{"WORLD"}, []string{key}},
+		{[]string{"release"}, []string{"01G7H0000000000000000000B1", "01G7H0000000000000000000B2"}},
+		{[]string{`"release plan"`}, []string{"01G7H0000000000000000000B1"}},
+		{[]string{"release", "-draft"}, []string{"01G7H0000000000000000000B1"}},
+		{[]string{"-release"}, []string{key}},
+		{[]string{"nowhere"}, []string{}},
+	}
+	for _, test := range tests {
+		f := pipeline.From(pipeline.PeriodAll, idxFile)
+		if f.Error.Err != nil {
+			t.Fatalf("want no error from From, got %q", f.Error.Err)
+		}
+		p := pipeline.Search(f.Reader, idxFile, test.query...)
+		if p.Error.Err != nil {
+			t.Fatalf("want no error from Search, got %q", p.Error.Err)
+		}
+		found, err := pipeline.ReadStream(p.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := []string{}
+		for _, entry := range found {
+			got = append(got, entry.Key)
+		}
+		sort.Strings(got)
+		if !cmp.Equal(test.want, got) {
+			t.Errorf("%q: %s", test.query, cmp.Diff(test.want, got))
+		}
+	}
+}
+
+func TestSearchRanks(t *testing.T) {
+	t.Parallel()
+	idxFile, _ := tempStore(t)
+	addMemo(t, idxFile, "01G7H0000000000000000000D1", nil, "Once upon a time there was a rollout, nothing else happened at all\n")
+	addMemo(t, idxFile, "01G7H0000000000000000000D2", nil, "Rollout of the rollout tooling\n")
+	addMemo(t, idxFile, "01G7H0000000000000000000D3", nil, "Rollout done\n")
+	f := pipeline.From(pipeline.PeriodAll, idxFile)
+	if f.Error.Err != nil {
+		t.Fatalf("want no error from From, got %q", f.Error.Err)
+	}
+	p := pipeline.Search(f.Reader, idxFile, "rollout")
+	if p.Error.Err != nil {
+		t.Fatalf("want no error from Search, got %q", p.Error.Err)
+	}
+	found, err := pipeline.ReadStream(p.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := []string{}
+	for _, entry := range found {
+		got = append(got, entry.Key)
+		if entry.Score <= 0 {
+			t.Errorf("want a positive score for %s, got %f", entry.Key, entry.Score)
+		}
+	}
+	want := []string{"01G7H0000000000000000000D2", "01G7H0000000000000000000D3", "01G7H0000000000000000000D1"}
+	if !cmp.Equal(want, got) {
+		t.Error(cmp.Diff(want, got))
+	}
+	r, err := pipeline.Marshal(found)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := pipeline.Stdout(r, "short", 1, idxFile)
+	if g.Error.Err != nil {
+		t.Fatalf("want no error from Stdout, got %q", g.Error.Err)
+	}
+	buf := &bytes.Buffer{}
+	g.Output = buf
+	g.Stdout()
+	if want := "\nRollout of the rollout tooling\n\n"; buf.String() != want {
+		t.Errorf("want %q printed only, got %q", want, buf.String())
+	}
+}
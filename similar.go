@@ -0,0 +1,109 @@
+package pipeline
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+//////////////////////////////////////////////////////////
+// Similar ... finds the memos most similar to a stored
+// one given by ref or to a memo from the pipe, by the
+// cosine of their term weights
+// used by similar
+//////////////////////////////////////////////////////////
+func Similar(rd io.Reader, ref string, limit int, filePath string) *Pipeline {
+	// Help wanted?
+	if ref == "help" {
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "SIMILAR is part of memo's famous toolbox")
+		fmt.Fprintln(os.Stderr, "----------------------------------------")
+		fmt.Fprintln(os.Stderr, "Usage: similar <ref> [--limit <n>] | further tools")
+		fmt.Fprintln(os.Stderr, "       memo | similar [--limit <n>] | further tools")
+		fmt.Fprintln(os.Stderr, "       similar help")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "It looks for the memos most similar to a stored memo or to a new one")
+		fmt.Fprintln(os.Stderr, "from the pipe, before it gets kept. Words count by their weight against")
+		fmt.Fprintln(os.Stderr, "all memos in the store. The index entries of the closest memos (default")
+		fmt.Fprintln(os.Stderr, "10) get piped to the next tool in the chain, most similar first, with")
+		fmt.Fprintln(os.Stderr, "their similarity between 0 and 1 as score. stdout long shows them.")
+		fmt.Fprintln(os.Stderr)
+		toolbox()
+		os.Exit(0)
+	}
+	if filePath == "" {
+		filePath = TakeMeHome(basePath + string(os.PathSeparator) + indexFile)
+	}
+	idx := NewIndex()
+	err := idx.Load(filePath)
+	if err != nil {
+		return &Pipeline{
+			Error: MaskedError{
+				Prefix: "✘ error ... ",
+				Err:    err,
+			},
+		}
+	}
+	memo := Memo{}
+	if ref != "" {
+		key, err := idx.Resolve(ref)
+		if err != nil {
+			return &Pipeline{
+				Error: MaskedError{
+					Prefix: "✘ error ... ",
+					Err:    err,
+				},
+			}
+		}
+		err = memo.load(idx.Find(key).Path, key)
+		if err != nil {
+			return &Pipeline{
+				Error: MaskedError{
+					Prefix: "✘ error ... ",
+					Err:    err,
+				},
+			}
+		}
+		// Legacy memos know their key by the index only
+		memo.ID = key
+	} else {
+		err = Unmarshal(rd, &memo)
+		if err != nil {
+			return &Pipeline{
+				Error: MaskedError{
+					Prefix: "✘ error ... ",
+					Err:    err,
+				},
+			}
+		}
+	}
+	c, err := loadCorpus(idx)
+	if err != nil {
+		return &Pipeline{
+			Error: MaskedError{
+				Prefix: "✘ error ... ",
+				Err:    err,
+			},
+		}
+	}
+
+	found := Stream{}
+	weights := c.weights(newDocument(memo.Content, memo.Tags))
+	for _, n := range c.neighbours(weights, memo.ID) {
+		found = append(found, StreamEntry{Key: n.key, Score: n.sim, IndexEntry: idx.Find(n.key)})
+	}
+
+	filtered, err := Marshal(found.Limit(limit))
+	if err != nil {
+		return &Pipeline{
+			Error: MaskedError{
+				Prefix: "✘ error ... ",
+				Err:    err,
+			},
+		}
+	}
+
+	return &Pipeline{
+		Reader: filtered,
+	}
+}
@@ -0,0 +1,54 @@
+package pipeline_test
+
+import (
+	"bytes"
+	"pipeline"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSimilar(t *testing.T) {
+	t.Parallel()
+	idxFile, key := tempStore(t)
+	addMemo(t, idxFile, "01G7H0000000000000000000J1", []string{"db"}, "Postgres replication lag after the failover\n")
+	addMemo(t, idxFile, "01G7H0000000000000000000J2", []string{"db"}, "Postgres failover drill\n")
+	addMemo(t, idxFile, "01G7H0000000000000000000J3", []string{"garden"}, "Water the tomatoes\n")
+	p := pipeline.Similar(nil, "01G7H0000000000000000000J1", 10, idxFile)
+	if p.Error.Err != nil {
+		t.Fatalf("want no error from Similar, got %q", p.Error.Err)
+	}
+	found, err := pipeline.ReadStream(p.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 1 || found[0].Key != "01G7H0000000000000000000J2" {
+		t.Fatalf("want the failover drill only, got %v", found)
+	}
+	if found[0].Score <= 0 || found[0].Score >= 1 {
+		t.Errorf("want a similarity between 0 and 1, got %f", found[0].Score)
+	}
+
+	// A new memo from the pipe
+	memo := pipeline.Memo{Content: "Hello tomatoes, hello world\n"}
+	buf := &bytes.Buffer{}
+	err = memo.Write(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p = pipeline.Similar(buf, "", 1, idxFile)
+	if p.Error.Err != nil {
+		t.Fatalf("want no error from Similar, got %q", p.Error.Err)
+	}
+	found, err = pipeline.ReadStream(p.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := []string{}
+	for _, entry := range found {
+		got = append(got, entry.Key)
+	}
+	if want := []string{key}; !cmp.Equal(want, got) {
+		t.Error(cmp.Diff(want, got))
+	}
+}
@@ -0,0 +1,76 @@
+package pipeline
+
+import (
+	"bytes"
+	"io"
+	"sort"
+)
+
+// StreamEntry ... an index entry on its way through the pipe, searches
+// give it a score
+type StreamEntry struct {
+	Key   string
+	Score float64 `json:",omitempty"`
+	IndexEntry
+}
+
+// Stream ... the index entries piped from tool to tool, in order
+type Stream []StreamEntry
+
+// NewStream ... puts index entries into a stream ordered by their keys,
+// which is the order of their creation
+func NewStream(entries map[string]IndexEntry) Stream {
+	s := make(Stream, 0, len(entries))
+	for _, key := range sortedKeys(entries) {
+		s = append(s, StreamEntry{Key: key, IndexEntry: entries[key]})
+	}
+	return s
+}
+
+// ReadStream ... reads a stream from the pipe, index entries keyed by
+// an object like older tools pipe them are taken as well
+func ReadStream(rd io.Reader) (Stream, error) {
+	b, err := io.ReadAll(rd)
+	if err != nil {
+		return nil, err
+	}
+	if trimmed := bytes.TrimSpace(b); len(trimmed) > 0 && trimmed[0] == '{' {
+		entries := make(map[string]IndexEntry)
+		err = Unmarshal(bytes.NewReader(b), &entries)
+		if err != nil {
+			return nil, err
+		}
+		return NewStream(entries), nil
+	}
+	s := Stream{}
+	err = Unmarshal(bytes.NewReader(b), &s)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Entries ... the index entries of the stream by their keys
+func (s Stream) Entries() map[string]IndexEntry {
+	entries := make(map[string]IndexEntry, len(s))
+	for _, e := range s {
+		entries[e.Key] = e.IndexEntry
+	}
+	return entries
+}
+
+// Rank ... orders the stream by score, best first, equal scores keep
+// their order
+func (s Stream) Rank() {
+	sort.SliceStable(s, func(i, j int) bool {
+		return s[i].Score > s[j].Score
+	})
+}
+
+// Limit ... the first n entries of the stream, all for n < 1
+func (s Stream) Limit(n int) Stream {
+	if n < 1 || n >= len(s) {
+		return s
+	}
+	return s[:n]
+}
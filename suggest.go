@@ -0,0 +1,214 @@
+package pipeline
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// number of similar memos, whose tags count for suggestions
+	similarMemos = 5
+	// shortest keyword worth a tag
+	minKeyword = 3
+)
+
+// Suggestion ... a candidate tag for a memo
+type Suggestion struct {
+	Tag     string
+	Score   float64
+	Reasons []string
+}
+
+//////////////////////////////////////////////////////////
+// Suggest ... ranks candidate tags for a memo, which
+// comes by the pipe, by keywords and by the tags of
+// similar memos in the store
+// used by suggest
+//////////////////////////////////////////////////////////
+func Suggest(rd io.Reader, what string, limit int, filePath string) *Pipeline {
+	// Help wanted?
+	if what == "help" {
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "SUGGEST is part of memo's famous toolbox")
+		fmt.Fprintln(os.Stderr, "----------------------------------------")
+		fmt.Fprintln(os.Stderr, "Usage: memo | suggest [<number>]")
+		fmt.Fprintln(os.Stderr, "       suggest help")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "It takes a memo from the pipe and prints candidate tags with their")
+		fmt.Fprintln(os.Stderr, "score, best first (default 10). Keywords count by their weight against")
+		fmt.Fprintln(os.Stderr, "all memos in the store, the tags of similar memos by their similarity.")
+		fmt.Fprintln(os.Stderr, "Nothing gets changed.")
+		fmt.Fprintln(os.Stderr)
+		toolbox()
+		os.Exit(0)
+	}
+	memo := Memo{}
+	err := Unmarshal(rd, &memo)
+	if err != nil {
+		return &Pipeline{
+			Error: MaskedError{
+				Prefix: "✘ error ... ",
+				Err:    err,
+			},
+		}
+	}
+	if filePath == "" {
+		filePath = TakeMeHome(basePath + string(os.PathSeparator) + indexFile)
+	}
+	cfg, err := LoadConfig(filePath)
+	if err != nil {
+		return &Pipeline{
+			Error: MaskedError{
+				Prefix: "✘ error ... ",
+				Err:    err,
+			},
+		}
+	}
+	idx := NewIndex()
+	err = idx.Load(filePath)
+	if err != nil {
+		return &Pipeline{
+			Error: MaskedError{
+				Prefix: "✘ error ... ",
+				Err:    err,
+			},
+		}
+	}
+	c, err := loadCorpus(idx)
+	if err != nil {
+		return &Pipeline{
+			Error: MaskedError{
+				Prefix: "✘ error ... ",
+				Err:    err,
+			},
+		}
+	}
+	suggestions := c.suggest(memo, cfg)
+	if limit > 0 && len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+	width := 0
+	for _, s := range suggestions {
+		if n := utf8.RuneCountInString(s.Tag); n > width {
+			width = n
+		}
+	}
+	buf := &bytes.Buffer{}
+	for _, s := range suggestions {
+		fmt.Fprintf(buf, "%-*s  %.2f  %s\n", width, s.Tag, s.Score, strings.Join(s.Reasons, ", "))
+	}
+	return &Pipeline{
+		Reader: buf,
+	}
+}
+
+// suggest ... ranks candidate tags for a memo, the memo itself doesn't
+// count as part of the corpus. Tags of similar memos score up to 1,
+// keywords up to 0.5 and twice that once they are known as tags.
+func (c *corpus) suggest(memo Memo, cfg Config) []Suggestion {
+	own := make(map[string]bool)
+	for _, tag := range memo.Tags {
+		if tag, err := NormalizeTag(tag, cfg); err == nil {
+			own[tag] = true
+		}
+	}
+	others := &corpus{docs: make(map[string]document), df: make(map[string]int)}
+	known := make(map[string]bool)
+	for key, doc := range c.docs {
+		if key == memo.ID {
+			continue
+		}
+		others.add(key, doc)
+		for _, tag := range doc.tags {
+			known[tag] = true
+		}
+	}
+	weights := others.weights(newDocument(memo.Content, memo.Tags))
+
+	// Keywords by their weight
+	keywords := make(map[string]float64)
+	for term, w := range weights {
+		if utf8.RuneCountInString(term) < minKeyword || strings.Trim(term, "0123456789") == "" {
+			continue
+		}
+		tag, err := NormalizeTag(term, cfg)
+		if err != nil || own[tag] {
+			continue
+		}
+		if w > keywords[tag] {
+			keywords[tag] = w
+		}
+	}
+
+	// Tags of the most similar memos by their similarity
+	neighbours := others.neighbours(weights, "")
+	if len(neighbours) > similarMemos {
+		neighbours = neighbours[:similarMemos]
+	}
+	cooccurring := make(map[string]float64)
+	for _, n := range neighbours {
+		for _, tag := range others.docs[n.key].tags {
+			tag, err := NormalizeTag(tag, cfg)
+			if err != nil || own[tag] {
+				continue
+			}
+			cooccurring[tag] += n.sim
+		}
+	}
+
+	candidates := make(map[string]*Suggestion)
+	candidate := func(tag string) *Suggestion {
+		s, exist := candidates[tag]
+		if !exist {
+			s = &Suggestion{Tag: tag}
+			candidates[tag] = s
+		}
+		return s
+	}
+	maxKeyword := maxScore(keywords)
+	for tag, w := range keywords {
+		s := candidate(tag)
+		s.Score += w / maxKeyword / 2
+		s.Reasons = append(s.Reasons, "keyword")
+		if known[tag] {
+			s.Score += w / maxKeyword / 2
+			s.Reasons = append(s.Reasons, "known tag")
+		}
+	}
+	maxCooccurring := maxScore(cooccurring)
+	for tag, w := range cooccurring {
+		s := candidate(tag)
+		s.Score += w / maxCooccurring
+		s.Reasons = append(s.Reasons, "similar memos")
+	}
+	suggestions := make([]Suggestion, 0, len(candidates))
+	for _, s := range candidates {
+		suggestions = append(suggestions, *s)
+	}
+	sort.Slice(suggestions, func(i, j int) bool {
+		if suggestions[i].Score != suggestions[j].Score {
+			return suggestions[i].Score > suggestions[j].Score
+		}
+		return suggestions[i].Tag < suggestions[j].Tag
+	})
+	return suggestions
+}
+
+// maxScore ... the highest score of a map, 1 for an empty one
+func maxScore(scores map[string]float64) float64 {
+	highest := 0.0
+	for _, score := range scores {
+		if score > highest {
+			highest = score
+		}
+	}
+	if highest == 0 {
+		return 1
+	}
+	return highest
+}
@@ -0,0 +1,91 @@
+package pipeline_test
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"pipeline"
+	"strings"
+	"testing"
+)
+
+// addMemo ... stores another memo next to the one of tempStore
+func addMemo(t *testing.T, idxFile, key string, tags []string, content string) {
+	t.Helper()
+	dir := filepath.Dir(idxFile)
+	memo := pipeline.Memo{
+		ID:       key,
+		Tags:     tags,
+		Modified: "10.07.2022 08:00:00",
+		Content:  content,
+	}
+	checksum, err := memo.Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	memo.Checksum = checksum
+	fi, err := os.Create(filepath.Join(dir, key+".memo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fi.Close()
+	err = memo.Write(fi)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entryTags := make(map[string]bool)
+	for _, tag := range tags {
+		entryTags[tag] = true
+	}
+	err = pipeline.NewIndex().Update(idxFile, func(i *pipeline.Index) error {
+		i.Upsert(key, pipeline.IndexEntry{
+			Checksum: checksum,
+			Tags:     entryTags,
+			Path:     dir,
+			Modified: memo.Modified,
+		})
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	t.Parallel()
+	idxFile, _ := tempStore(t)
+	addMemo(t, idxFile, "01G7H0000000000000000000A1", []string{"db", "ops"}, "Postgres replication lag after the failover\n")
+	addMemo(t, idxFile, "01G7H0000000000000000000A2", []string{"db"}, "Vacuum the postgres tables every night\n")
+	addMemo(t, idxFile, "01G7H0000000000000000000A3", []string{"garden"}, "Water the tomatoes\n")
+	memo := pipeline.Memo{
+		Tags:    []string{"ops"},
+		Content: "Postgres failover drill went fine, replication caught up\n",
+	}
+	buf := &bytes.Buffer{}
+	err := memo.Write(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := pipeline.Suggest(buf, "", 3, idxFile)
+	if p.Error.Err != nil {
+		t.Fatalf("want no error from Suggest, got %q", p.Error.Err)
+	}
+	out, err := io.ReadAll(p.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("want 3 suggestions, got %q", lines)
+	}
+	if !strings.HasPrefix(lines[0], "db ") || !strings.Contains(lines[0], "similar memos") {
+		t.Errorf("want db from similar memos first, got %q", lines[0])
+	}
+	for _, line := range lines {
+		tag := strings.Fields(line)[0]
+		if tag == "ops" || tag == "garden" || tag == "the" {
+			t.Errorf("want no suggestion %q, got %q", tag, line)
+		}
+	}
+}
@@ -0,0 +1,659 @@
+package pipeline
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	// Characters, which separate tags in lists and expressions
+	tagSeparators = ",;()"
+
+	// Separates the levels of hierarchical tags
+	tagLevel = "/"
+)
+
+type (
+	// TagUsage ... how often and how recently a tag is used
+	TagUsage struct {
+		Tag      string
+		Count    int
+		LastUsed string
+	}
+
+	// tagNode ... a level of the tag hierarchy with the number of
+	// memos tagged by it or by any of its descendants
+	tagNode struct {
+		count    int
+		children map[string]*tagNode
+	}
+)
+
+//////////////////////////////////////////////////////////
+// Tags ... shows the tags of the memos in an index stream
+// as list, tree or plain names, lists are sorted by name,
+// count or recent use and may be formatted as JSON
+// used by tags
+//////////////////////////////////////////////////////////
+func Tags(rd io.Reader, what, sortBy, format string) *Pipeline {
+	// Help wanted?
+	if what == "help" {
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "TAGS is part of memo's famous toolbox")
+		fmt.Fprintln(os.Stderr, "-------------------------------------")
+		fmt.Fprintln(os.Stderr, "Usage: further tools | tags [<what>] [--sort <order>] [--json]")
+		fmt.Fprintln(os.Stderr, "       tags rename <old> <new>")
+		fmt.Fprintln(os.Stderr, "       tags alias [<synonym> <canonical>]")
+		fmt.Fprintln(os.Stderr, "       tags help")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "It takes a list of index entries for memos from the pipe and shows")
+		fmt.Fprintln(os.Stderr, "their tags. Without pipe it takes the whole index.")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Following views taken by <what> are supported by now ...")
+		fmt.Fprintln(os.Stderr, "  ✓ list ... tags with number of memos and last use (default)")
+		fmt.Fprintln(os.Stderr, "  ✓ tree ... hierarchy of the tags with the number of memos")
+		fmt.Fprintln(os.Stderr, "  ✓ names ... tag names only, one per line for shell completion")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "tags names is the hook for shell completion of tagit and tagged, the")
+		fmt.Fprintln(os.Stderr, "README shows how to wire it up in bash.")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Following orders taken by --sort are supported by now ...")
+		fmt.Fprintln(os.Stderr, "  ✓ name ... alphabetically (default)")
+		fmt.Fprintln(os.Stderr, "  ✓ count ... most used tags first")
+		fmt.Fprintln(os.Stderr, "  ✓ recent ... last used tags first")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "With --json lists are printed as JSON array.")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Renaming changes a tag and all its descendants in every memo of the")
+		fmt.Fprintln(os.Stderr, "store. Aliases map synonyms to a canonical tag, which is used by tagit")
+		fmt.Fprintln(os.Stderr, "and tagged instead. Without arguments alias lists all aliases.")
+		fmt.Fprintln(os.Stderr)
+		toolbox()
+		os.Exit(0)
+	}
+	stream, err := ReadStream(rd)
+	if err != nil {
+		return &Pipeline{
+			Error: MaskedError{
+				Prefix: "✘ error ... ",
+				Err:    err,
+			},
+		}
+	}
+	entries := stream.Entries()
+	if what == "tree" {
+		root := &tagNode{children: make(map[string]*tagNode)}
+		for _, entry := range entries {
+			root.add(entry.Tags)
+		}
+		buf := &bytes.Buffer{}
+		root.print(buf, 0)
+		return &Pipeline{
+			Reader: buf,
+		}
+	}
+	if what != "" && what != "list" && what != "names" {
+		return &Pipeline{
+			Error: MaskedError{
+				Prefix: "✘ error ... ",
+				Err:    fmt.Errorf("unknown view %q", what),
+			},
+		}
+	}
+	usage, err := tagUsage(entries, sortBy)
+	if err != nil {
+		return &Pipeline{
+			Error: MaskedError{
+				Prefix: "✘ error ... ",
+				Err:    err,
+			},
+		}
+	}
+	if format == "json" {
+		r, err := Marshal(usage)
+		if err != nil {
+			return &Pipeline{
+				Error: MaskedError{
+					Prefix: "✘ error ... ",
+					Err:    err,
+				},
+			}
+		}
+		return &Pipeline{
+			Reader: r,
+		}
+	}
+	width, countWidth := 0, 0
+	for _, u := range usage {
+		if n := utf8.RuneCountInString(u.Tag); n > width {
+			width = n
+		}
+		if n := len(strconv.Itoa(u.Count)); n > countWidth {
+			countWidth = n
+		}
+	}
+	buf := &bytes.Buffer{}
+	for _, u := range usage {
+		if what == "names" {
+			fmt.Fprintln(buf, u.Tag)
+			continue
+		}
+		lastUsed := u.LastUsed
+		if lastUsed == "" {
+			lastUsed = "unknown"
+		}
+		fmt.Fprintf(buf, "%-*s  %*d  %s\n", width, u.Tag, countWidth, u.Count, lastUsed)
+	}
+	return &Pipeline{
+		Reader: buf,
+	}
+}
+
+//////////////////////////////////////////////////////////
+// Retag ... adds and removes tags of stored memos, which
+// come by an index stream, changes look like +tag or -tag
+// used by retag
+//////////////////////////////////////////////////////////
+func Retag(rd io.Reader, changes []string, filePath string) *Pipeline {
+	// Help wanted?
+	if len(changes) > 0 && changes[0] == "help" {
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "RETAG is part of memo's famous toolbox")
+		fmt.Fprintln(os.Stderr, "--------------------------------------")
+		fmt.Fprintln(os.Stderr, "Usage: further tools | retag +<tag> -<tag> [+<tagn> -<tagn>] | further tools")
+		fmt.Fprintln(os.Stderr, "       retag help")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "It takes a list of index entries for memos from the pipe and changes")
+		fmt.Fprintln(os.Stderr, "their tags. Tags with a leading + get added, tags with a leading -")
+		fmt.Fprintln(os.Stderr, "get removed. The memo files and the index are updated both, the")
+		fmt.Fprintln(os.Stderr, "changed entries get piped to the next tool in the chain ...")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "  from all | tagged draft | retag -draft +published | stdout")
+		fmt.Fprintln(os.Stderr)
+		toolbox()
+		os.Exit(0)
+	}
+	add := []string{}
+	remove := []string{}
+	for _, change := range changes {
+		switch {
+		case len(change) > 1 && change[0] == '+':
+			add = append(add, change[1:])
+		case len(change) > 1 && change[0] == '-':
+			remove = append(remove, change[1:])
+		default:
+			return &Pipeline{
+				Error: MaskedError{
+					Prefix: "✘ error ... ",
+					Err:    fmt.Errorf("invalid tag change %q, want +tag or -tag", change),
+				},
+			}
+		}
+	}
+	if filePath == "" {
+		filePath = TakeMeHome(basePath + string(os.PathSeparator) + indexFile)
+	}
+	cfg, err := LoadConfig(filePath)
+	if err != nil {
+		return &Pipeline{
+			Error: MaskedError{
+				Prefix: "✘ error ... ",
+				Err:    err,
+			},
+		}
+	}
+	add, err = NormalizeTags(add, cfg)
+	if err != nil {
+		return &Pipeline{
+			Error: MaskedError{
+				Prefix: "✘ error ... ",
+				Err:    err,
+			},
+		}
+	}
+	// Stored tags may be synonyms or older spellings, both go
+	plain := Config{KeepCase: cfg.KeepCase}
+	removed := make(map[string]bool)
+	for _, tag := range remove {
+		for _, c := range []Config{plain, cfg} {
+			tag, err := NormalizeTag(tag, c)
+			if err != nil {
+				return &Pipeline{
+					Error: MaskedError{
+						Prefix: "✘ error ... ",
+						Err:    err,
+					},
+				}
+			}
+			removed[tag] = true
+		}
+	}
+	entries, err := ReadStream(rd)
+	if err != nil {
+		return &Pipeline{
+			Error: MaskedError{
+				Prefix: "✘ error ... ",
+				Err:    err,
+			},
+		}
+	}
+	keys := []string{}
+	for _, piped := range entries {
+		keys = append(keys, piped.Key)
+	}
+	idx, _, err := rewriteMemos(filePath, keys, nil, func(_ string, memo *Memo) (bool, error) {
+		kept := []string{}
+		for _, tag := range memo.Tags {
+			if removedTag(tag, removed, plain, cfg) {
+				continue
+			}
+			kept = append(kept, tag)
+		}
+		tags, changed := changeTags(kept, add, nil)
+		changed = changed || len(kept) != len(memo.Tags)
+		memo.Tags = tags
+		return changed, nil
+	})
+	if err != nil {
+		return &Pipeline{
+			Error: MaskedError{
+				Prefix: "✘ error ... ",
+				Err:    err,
+			},
+		}
+	}
+	retagged := Stream{}
+	for _, piped := range entries {
+		retagged = append(retagged, StreamEntry{Key: piped.Key, Score: piped.Score, IndexEntry: idx.Find(piped.Key)})
+	}
+	r, err := Marshal(retagged)
+	if err != nil {
+		return &Pipeline{
+			Error: MaskedError{
+				Prefix: "✘ error ... ",
+				Err:    err,
+			},
+		}
+	}
+	return &Pipeline{
+		Reader: r,
+	}
+}
+
+//////////////////////////////////////////////////////////
+// NormalizeTags ... splits comma separated lists of tags,
+// normalises each tag, drops the duplicates and sorts them
+//////////////////////////////////////////////////////////
+func NormalizeTags(tags []string, cfg Config) ([]string, error) {
+	set := make(map[string]bool)
+	for _, list := range tags {
+		for _, tag := range strings.Split(list, ",") {
+			tag, err := NormalizeTag(tag, cfg)
+			if err != nil {
+				return nil, err
+			}
+			set[tag] = true
+		}
+	}
+	normalized := make([]string, 0, len(set))
+	for tag := range set {
+		normalized = append(normalized, tag)
+	}
+	sort.Strings(normalized)
+	return normalized, nil
+}
+
+//////////////////////////////////////////////////////////
+// NormalizeTag ... trims a tag, case-folds it unless the
+// config keeps the case and brings it into Unicode's NFC,
+// tags must neither be empty nor contain separators, that
+// goes for every level of hierarchical tags as well, last
+// synonyms are replaced by their canonical tag
+//////////////////////////////////////////////////////////
+func NormalizeTag(tag string, cfg Config) (string, error) {
+	raw := tag
+	tag = strings.TrimSpace(tag)
+	if !cfg.KeepCase {
+		tag = cases.Fold().String(tag)
+	}
+	tag = norm.NFC.String(tag)
+	if tag == "" {
+		return "", &TagError{Tag: raw, Reason: "tags must not be empty"}
+	}
+	if i := strings.IndexFunc(tag, func(r rune) bool {
+		return unicode.IsSpace(r) || strings.ContainsRune(tagSeparators, r)
+	}); i >= 0 {
+		r, _ := utf8.DecodeRuneInString(tag[i:])
+		return "", &TagError{Tag: raw, Reason: fmt.Sprintf("separator %q not allowed", r)}
+	}
+	for _, level := range strings.Split(tag, tagLevel) {
+		if level == "" {
+			return "", &TagError{Tag: raw, Reason: "levels of hierarchical tags must not be empty"}
+		}
+	}
+	return cfg.canonical(tag), nil
+}
+
+// tagUsage ... counts the tags of the entries and finds their last use
+func tagUsage(entries map[string]IndexEntry, sortBy string) ([]TagUsage, error) {
+	usage := make(map[string]*TagUsage)
+	last := make(map[string]time.Time)
+	for _, entry := range entries {
+		// A broken date leaves the last use unknown, but the tags count
+		modified, err := time.ParseInLocation(timeLayout, entry.Modified, time.Local)
+		for tag := range entry.Tags {
+			u, exist := usage[tag]
+			if !exist {
+				u = &TagUsage{Tag: tag}
+				usage[tag] = u
+			}
+			u.Count++
+			if err == nil && modified.After(last[tag]) {
+				last[tag] = modified
+				u.LastUsed = entry.Modified
+			}
+		}
+	}
+	list := make([]TagUsage, 0, len(usage))
+	for _, u := range usage {
+		list = append(list, *u)
+	}
+	var less func(a, b int) bool
+	switch sortBy {
+	case "", "name":
+		less = func(a, b int) bool { return list[a].Tag < list[b].Tag }
+	case "count":
+		less = func(a, b int) bool {
+			if list[a].Count != list[b].Count {
+				return list[a].Count > list[b].Count
+			}
+			return list[a].Tag < list[b].Tag
+		}
+	case "recent":
+		less = func(a, b int) bool {
+			if !last[list[a].Tag].Equal(last[list[b].Tag]) {
+				return last[list[a].Tag].After(last[list[b].Tag])
+			}
+			return list[a].Tag < list[b].Tag
+		}
+	default:
+		return nil, fmt.Errorf("unknown order %q", sortBy)
+	}
+	sort.Slice(list, less)
+	return list, nil
+}
+
+// hasTag ... tells if tag is one of tags or, if not exact, an
+// ancestor of one of them
+func hasTag(tags map[string]bool, tag string, exact bool) bool {
+	if tags[tag] {
+		return true
+	}
+	if exact {
+		return false
+	}
+	for candidate := range tags {
+		if strings.HasPrefix(candidate, tag+tagLevel) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizedTags ... the tags of an entry as they would be tagged
+// today, tags from older tools may not be normalised yet
+func (e IndexEntry) normalizedTags(cfg Config) map[string]bool {
+	tags := make(map[string]bool, len(e.Tags))
+	for tag, set := range e.Tags {
+		if normalized, err := NormalizeTag(tag, cfg); err == nil {
+			tag = normalized
+		}
+		tags[tag] = set
+	}
+	return tags
+}
+
+// add ... counts a memo for every level of its tags, but only once
+// per node, even if several tags share it
+func (n *tagNode) add(tags map[string]bool) {
+	counted := make(map[*tagNode]bool)
+	for tag := range tags {
+		node := n
+		for _, level := range strings.Split(tag, tagLevel) {
+			child, exist := node.children[level]
+			if !exist {
+				child = &tagNode{children: make(map[string]*tagNode)}
+				node.children[level] = child
+			}
+			if !counted[child] {
+				child.count++
+				counted[child] = true
+			}
+			node = child
+		}
+	}
+}
+
+// print ... writes the children of the node indented by depth
+func (n *tagNode) print(w io.Writer, depth int) {
+	names := make([]string, 0, len(n.children))
+	for name := range n.children {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		child := n.children[name]
+		fmt.Fprintf(w, "%s%s (%d)\n", strings.Repeat("  ", depth), name, child.count)
+		child.print(w, depth+1)
+	}
+}
+
+//////////////////////////////////////////////////////////
+// RenameTag ... renames a tag and all its descendants in
+// every memo file and index entry of the store, either all
+// of them change or none, returns the number of memos
+// used by tags
+//////////////////////////////////////////////////////////
+func RenameTag(from, to, filePath string) (int, error) {
+	if filePath == "" {
+		filePath = TakeMeHome(basePath + string(os.PathSeparator) + indexFile)
+	}
+	cfg, err := LoadConfig(filePath)
+	if err != nil {
+		return 0, err
+	}
+	// Synonyms must not be resolved here, old tags would vanish
+	plain := Config{KeepCase: cfg.KeepCase}
+	from, err = NormalizeTag(from, plain)
+	if err != nil {
+		return 0, err
+	}
+	to, err = NormalizeTag(to, plain)
+	if err != nil {
+		return 0, err
+	}
+	// Stored tags may be spelled the old way, like K8S for k8s
+	rename := func(tag string) (string, bool) {
+		normalized, err := NormalizeTag(tag, plain)
+		if err != nil {
+			return tag, false
+		}
+		if normalized == from {
+			return to, true
+		}
+		if strings.HasPrefix(normalized, from+tagLevel) {
+			return to + normalized[len(from):], true
+		}
+		return tag, false
+	}
+
+	_, changed, err := rewriteMemos(filePath, nil, func(entry IndexEntry) bool {
+		for tag := range entry.Tags {
+			if _, renamed := rename(tag); renamed {
+				return true
+			}
+		}
+		return false
+	}, func(_ string, memo *Memo) (bool, error) {
+		tags := []string{}
+		for _, tag := range memo.Tags {
+			tag, _ = rename(tag)
+			tags = append(tags, tag)
+		}
+		memo.Tags, _ = changeTags(tags, nil, nil)
+		return true, nil
+	})
+	return changed, err
+}
+
+// rewriteMemos ... changes the memos with keys, all for nil keys, which
+// are wanted, wanted nil means all. Either all changed memo files and
+// the index get replaced or none, the prior versions get archived.
+// Returns the updated index and the number of changed memos.
+func rewriteMemos(filePath string, keys []string, wanted func(entry IndexEntry) bool, change func(key string, memo *Memo) (bool, error)) (*Index, int, error) {
+	lock.Lock()
+	defer lock.Unlock()
+	idx := NewIndex()
+	err := idx.load(filePath)
+	if err != nil {
+		return nil, 0, err
+	}
+	if keys == nil {
+		keys = idx.Keys()
+	}
+	type rewrite struct {
+		key, dir, tmp string
+		old           []byte
+		rev           int
+	}
+	rewrites := []rewrite{}
+	base := filepath.Dir(filePath)
+	// Roll back whatever happened so far
+	rollback := func(replaced int) {
+		for n, r := range rewrites {
+			if r.rev > 0 {
+				os.Remove(filepath.Join(base, historyDir, r.key, strconv.Itoa(r.rev)+".memo"))
+			}
+			if n < replaced {
+				os.WriteFile(r.dir+string(os.PathSeparator)+r.key+".memo", r.old, 0o644)
+			} else {
+				os.Remove(r.tmp)
+			}
+		}
+	}
+	// First write all new memos into temporary files
+	for _, key := range keys {
+		entry, exist := idx.entries[key]
+		if !exist {
+			rollback(0)
+			return nil, 0, fmt.Errorf("index entry for key %s does not exist", key)
+		}
+		if wanted != nil && !wanted(entry) {
+			continue
+		}
+		old, err := os.ReadFile(entry.Path + string(os.PathSeparator) + key + ".memo")
+		if err != nil {
+			rollback(0)
+			return nil, 0, err
+		}
+		memo := Memo{}
+		err = memo.Read(bytes.NewReader(old))
+		if err != nil {
+			rollback(0)
+			return nil, 0, err
+		}
+		changed, err := change(key, &memo)
+		if err != nil {
+			rollback(0)
+			return nil, 0, err
+		}
+		if !changed {
+			continue
+		}
+		tmp, err := os.CreateTemp(entry.Path, key+".*")
+		if err != nil {
+			rollback(0)
+			return nil, 0, err
+		}
+		rewrites = append(rewrites, rewrite{key: key, dir: entry.Path, tmp: tmp.Name(), old: old})
+		err = memo.Write(tmp)
+		if err == nil {
+			err = tmp.Close()
+		} else {
+			tmp.Close()
+		}
+		if err == nil {
+			err = os.Chmod(tmp.Name(), 0o644)
+		}
+		if err != nil {
+			rollback(0)
+			return nil, 0, err
+		}
+		fresh := memo.entry(entry.Path)
+		fresh.Deleted = entry.Deleted
+		idx.entries[key] = fresh
+	}
+	// Then replace the memos and the index
+	for n, r := range rewrites {
+		// Keep the prior version instead of overwriting it
+		rewrites[n].rev, err = archive(base, r.key, r.dir)
+		if err != nil {
+			rollback(n)
+			return nil, 0, err
+		}
+		err = os.Rename(r.tmp, r.dir+string(os.PathSeparator)+r.key+".memo")
+		if err != nil {
+			rollback(n)
+			return nil, 0, err
+		}
+	}
+	err = idx.store(filePath)
+	if err != nil {
+		rollback(len(rewrites))
+		return nil, 0, err
+	}
+	return idx, len(rewrites), nil
+}
+
+// removedTag ... tells whether a stored tag is one of the removed ones,
+// either as it is spelled or by its canonical tag
+func removedTag(tag string, removed map[string]bool, plain, cfg Config) bool {
+	for _, c := range []Config{plain, cfg} {
+		if normalized, err := NormalizeTag(tag, c); err == nil && removed[normalized] {
+			return true
+		}
+	}
+	return false
+}
+
+// changeTags ... removes and adds tags, the result is sorted
+func changeTags(tags, add, remove []string) ([]string, bool) {
+	set := make(map[string]bool)
+	for _, tag := range tags {
+		set[tag] = true
+	}
+	for _, tag := range remove {
+		delete(set, tag)
+	}
+	for _, tag := range add {
+		set[tag] = true
+	}
+	changed := []string{}
+	for tag := range set {
+		changed = append(changed, tag)
+	}
+	sort.Strings(changed)
+	return changed, strings.Join(changed, "\n") != strings.Join(tags, "\n")
+}
@@ -0,0 +1,504 @@
+package pipeline_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"pipeline"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRetag(t *testing.T) {
+	t.Parallel()
+	idxFile, key := tempStore(t)
+	f := pipeline.From(pipeline.PeriodAll, idxFile)
+	if f.Error.Err != nil {
+		t.Fatalf("want no error from From, got %q", f.Error.Err)
+	}
+	g := pipeline.Tagged(f.Reader, idxFile, "test")
+	if g.Error.Err != nil {
+		t.Fatalf("want no error from Tagged, got %q", g.Error.Err)
+	}
+	p := pipeline.Retag(g.Reader, []string{"-test", "+published", "+done"}, idxFile)
+	if p.Error.Err != nil {
+		t.Fatalf("want no error from Retag, got %q", p.Error.Err)
+	}
+	stream, err := pipeline.ReadStream(p.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := stream.Entries()
+	want := map[string]bool{"done": true, "published": true}
+	if !cmp.Equal(want, entries[key].Tags) {
+		t.Error(cmp.Diff(want, entries[key].Tags))
+	}
+	idx := pipeline.NewIndex()
+	err = idx.Load(idxFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cmp.Equal(want, idx.Find(key).Tags) {
+		t.Error(cmp.Diff(want, idx.Find(key).Tags))
+	}
+	fi, err := os.Open(filepath.Join(filepath.Dir(idxFile), key+".memo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fi.Close()
+	memo := pipeline.Memo{}
+	err = memo.Read(fi)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cmp.Equal([]string{"done", "published"}, memo.Tags) {
+		t.Error(cmp.Diff([]string{"done", "published"}, memo.Tags))
+	}
+}
+
+func TestRetagInvalidChange(t *testing.T) {
+	t.Parallel()
+	p := pipeline.Retag(nil, []string{"draft"}, "testdata/index.dat")
+	if p.Error.Err == nil {
+		t.Fatal("want error for a change without + or -, got none")
+	}
+}
+
+func TestRetagRemovesSynonyms(t *testing.T) {
+	t.Parallel()
+	idxFile, key := tempStore(t)
+	addMemo(t, idxFile, "01G7GVMW9R5Y3X8K7JCD0TQ2ZC", []string{"k8s", "ops"}, "Cluster upgrade\n")
+	err := pipeline.SetAlias("k8s", "kubernetes", idxFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := pipeline.From(pipeline.PeriodAll, idxFile)
+	if f.Error.Err != nil {
+		t.Fatalf("want no error from From, got %q", f.Error.Err)
+	}
+	p := pipeline.Retag(f.Reader, []string{"-k8s"}, idxFile)
+	if p.Error.Err != nil {
+		t.Fatalf("want no error from Retag, got %q", p.Error.Err)
+	}
+	idx := pipeline.NewIndex()
+	err = idx.Load(idxFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]bool{"ops": true}
+	if got := idx.Find("01G7GVMW9R5Y3X8K7JCD0TQ2ZC").Tags; !cmp.Equal(want, got) {
+		t.Error(cmp.Diff(want, got))
+	}
+	want = map[string]bool{"test": true}
+	if got := idx.Find(key).Tags; !cmp.Equal(want, got) {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestRetagRollsBack(t *testing.T) {
+	t.Parallel()
+	idxFile, key := tempStore(t)
+	memoFile := filepath.Join(filepath.Dir(idxFile), key+".memo")
+	before, err := os.ReadFile(memoFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream := pipeline.Stream{
+		{Key: key},
+		{Key: "01G7GVMW9R5Y3X8K7JCD0TQ2ZX"},
+	}
+	r, err := pipeline.Marshal(stream)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := pipeline.Retag(r, []string{"+done"}, idxFile)
+	if p.Error.Err == nil {
+		t.Fatal("want error for a missing index entry, got none")
+	}
+	after, err := os.ReadFile(memoFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cmp.Equal(string(before), string(after)) {
+		t.Error(cmp.Diff(string(before), string(after)))
+	}
+	files, err := filepath.Glob(filepath.Join(filepath.Dir(idxFile), key+".*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Errorf("want no temporary files left, got %q", files)
+	}
+}
+
+func TestTagItNormalizes(t *testing.T) {
+	t.Parallel()
+	m := pipeline.Memo{Modified: "09.07.2022 22:26:15", Content: "Hello world\n", Tags: []string{"work"}}
+	r, err := pipeline.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The second café is composed of e and the combining acute accent
+	p := pipeline.TagIt(r, "testdata/index.dat", " Work ", "k8s,Caf\u00e9", "cafe\u0301", "K8S")
+	if p.Error.Err != nil {
+		t.Fatalf("want no error from TagIt, got %q", p.Error.Err)
+	}
+	got := pipeline.Memo{}
+	err = pipeline.Unmarshal(p.Reader, &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"caf\u00e9", "k8s", "work"}
+	if !cmp.Equal(want, got.Tags) {
+		t.Error(cmp.Diff(want, got.Tags))
+	}
+}
+
+func TestNormalizeTagInvalid(t *testing.T) {
+	t.Parallel()
+	for _, tag := range []string{"", "  ", "two words", "semi;colon", "(group)", "project//alpha", "/root"} {
+		_, err := pipeline.NormalizeTag(tag, pipeline.Config{})
+		tagErr := &pipeline.TagError{}
+		if !errors.As(err, &tagErr) {
+			t.Errorf("want a tag error for %q, got %v", tag, err)
+		}
+	}
+}
+
+func TestNormalizeTagKeepCase(t *testing.T) {
+	t.Parallel()
+	got, err := pipeline.NormalizeTag("K8S", pipeline.Config{KeepCase: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "K8S" {
+		t.Errorf("want case kept, got %q", got)
+	}
+}
+
+// hierarchy ... an index stream with hierarchical tags
+func hierarchy(t *testing.T) map[string]pipeline.IndexEntry {
+	t.Helper()
+	return map[string]pipeline.IndexEntry{
+		"A": {Tags: map[string]bool{"project/alpha/design": true, "work": true}},
+		"B": {Tags: map[string]bool{"project/alpha": true}},
+		"C": {Tags: map[string]bool{"project/beta": true, "project/alpha/review": true}},
+		"D": {Tags: map[string]bool{"private": true}},
+	}
+}
+
+func TestTaggedHierarchy(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		tags []string
+		want []string
+	}{
+		{[]string{"project/alpha"}, []string{"A", "B", "C"}},
+		{[]string{"--exact", "project/alpha"}, []string{"B"}},
+		{[]string{"project"}, []string{"A", "B", "C"}},
+		{[]string{"project/alpha", "work"}, []string{"A"}},
+		{[]string{"project/al"}, []string{}},
+		{[]string{"work and not private"}, []string{"A"}},
+		{[]string{"project", "not work"}, []string{"B", "C"}},
+		{[]string{"work", "private or project/alpha"}, []string{"A"}},
+		{[]string{"(private or work)"}, []string{"A", "D"}},
+		{[]string{}, []string{"A", "B", "C", "D"}},
+		{[]string{"--exact"}, []string{"A", "B", "C", "D"}},
+	}
+	for _, test := range tests {
+		r, err := pipeline.Marshal(hierarchy(t))
+		if err != nil {
+			t.Fatal(err)
+		}
+		p := pipeline.Tagged(r, "testdata/index.dat", test.tags...)
+		if p.Error.Err != nil {
+			t.Fatalf("want no error from Tagged, got %q", p.Error.Err)
+		}
+		found, err := pipeline.ReadStream(p.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := []string{}
+		for _, entry := range found {
+			got = append(got, entry.Key)
+		}
+		if !cmp.Equal(test.want, got) {
+			t.Errorf("%q: %s", test.tags, cmp.Diff(test.want, got))
+		}
+	}
+}
+
+func TestTaggedKeywords(t *testing.T) {
+	t.Parallel()
+	entries := map[string]pipeline.IndexEntry{
+		"A": {Tags: map[string]bool{"not": true, "or": true}},
+		"B": {Tags: map[string]bool{"or": true}},
+		"C": {Tags: map[string]bool{"work": true}},
+	}
+	tests := []struct {
+		tags []string
+		want []string
+	}{
+		{[]string{"not"}, []string{"A"}},
+		{[]string{"or", "not"}, []string{"A"}},
+		{[]string{"or"}, []string{"A", "B"}},
+		{[]string{"not work"}, []string{"A", "B"}},
+		{[]string{"or", "not work"}, []string{"A", "B"}},
+	}
+	for _, test := range tests {
+		r, err := pipeline.Marshal(entries)
+		if err != nil {
+			t.Fatal(err)
+		}
+		p := pipeline.Tagged(r, "testdata/index.dat", test.tags...)
+		if p.Error.Err != nil {
+			t.Fatalf("want no error from Tagged, got %q", p.Error.Err)
+		}
+		found, err := pipeline.ReadStream(p.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := []string{}
+		for _, entry := range found {
+			got = append(got, entry.Key)
+		}
+		if !cmp.Equal(test.want, got) {
+			t.Errorf("%q: %s", test.tags, cmp.Diff(test.want, got))
+		}
+	}
+}
+
+func TestTagsTree(t *testing.T) {
+	t.Parallel()
+	r, err := pipeline.Marshal(hierarchy(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := pipeline.Tags(r, "tree", "name", "text")
+	if p.Error.Err != nil {
+		t.Fatalf("want no error from Tags, got %q", p.Error.Err)
+	}
+	buf := &bytes.Buffer{}
+	p.Output = buf
+	p.Stdout()
+	want := `private (1)
+project (3)
+  alpha (3)
+    design (1)
+    review (1)
+  beta (1)
+work (1)
+
+`
+	if !cmp.Equal(want, buf.String()) {
+		t.Error(cmp.Diff(want, buf.String()))
+	}
+}
+
+func TestTagsList(t *testing.T) {
+	t.Parallel()
+	entries := map[string]pipeline.IndexEntry{
+		"A": {Tags: map[string]bool{"work": true, "db": true}, Modified: "01.06.2022 10:00:00"},
+		"B": {Tags: map[string]bool{"work": true}, Modified: "02.06.2022 10:00:00"},
+		"C": {Tags: map[string]bool{"private": true}, Modified: "03.06.2022 10:00:00"},
+	}
+	tests := map[string]string{
+		"name":   "db       1  01.06.2022 10:00:00\nprivate  1  03.06.2022 10:00:00\nwork     2  02.06.2022 10:00:00\n\n",
+		"count":  "work     2  02.06.2022 10:00:00\ndb       1  01.06.2022 10:00:00\nprivate  1  03.06.2022 10:00:00\n\n",
+		"recent": "private  1  03.06.2022 10:00:00\nwork     2  02.06.2022 10:00:00\ndb       1  01.06.2022 10:00:00\n\n",
+	}
+	for sortBy, want := range tests {
+		r, err := pipeline.Marshal(entries)
+		if err != nil {
+			t.Fatal(err)
+		}
+		p := pipeline.Tags(r, "list", sortBy, "text")
+		if p.Error.Err != nil {
+			t.Fatalf("want no error from Tags, got %q", p.Error.Err)
+		}
+		buf := &bytes.Buffer{}
+		p.Output = buf
+		p.Stdout()
+		if !cmp.Equal(want, buf.String()) {
+			t.Errorf("sorted by %s: %s", sortBy, cmp.Diff(want, buf.String()))
+		}
+	}
+	r, err := pipeline.Marshal(entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := pipeline.Tags(r, "list", "count", "json")
+	if p.Error.Err != nil {
+		t.Fatalf("want no error from Tags, got %q", p.Error.Err)
+	}
+	usage := []pipeline.TagUsage{}
+	err = pipeline.Unmarshal(p.Reader, &usage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := pipeline.TagUsage{Tag: "work", Count: 2, LastUsed: "02.06.2022 10:00:00"}
+	if len(usage) != 3 || !cmp.Equal(want, usage[0]) {
+		t.Errorf("want %v first, got %v", want, usage)
+	}
+}
+
+func TestTagsListBrokenDate(t *testing.T) {
+	t.Parallel()
+	entries := map[string]pipeline.IndexEntry{
+		"A": {Tags: map[string]bool{"work": true}, Modified: "01.06.2022 10:00:00"},
+		"B": {Tags: map[string]bool{"work": true, "db": true}, Modified: "yesterday"},
+	}
+	r, err := pipeline.Marshal(entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := pipeline.Tags(r, "list", "recent", "text")
+	if p.Error.Err != nil {
+		t.Fatalf("want no error from Tags, got %q", p.Error.Err)
+	}
+	buf := &bytes.Buffer{}
+	p.Output = buf
+	p.Stdout()
+	want := "work  2  01.06.2022 10:00:00\ndb    1  unknown\n\n"
+	if !cmp.Equal(want, buf.String()) {
+		t.Error(cmp.Diff(want, buf.String()))
+	}
+}
+
+func TestRenameTag(t *testing.T) {
+	t.Parallel()
+	idxFile, key := tempStore(t)
+	renamed, err := pipeline.RenameTag("Test", "quality/test", idxFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if renamed != 1 {
+		t.Errorf("want 1 renamed memo, got %d", renamed)
+	}
+	idx := pipeline.NewIndex()
+	err = idx.Load(idxFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]bool{"quality/test": true}
+	if !cmp.Equal(want, idx.Find(key).Tags) {
+		t.Error(cmp.Diff(want, idx.Find(key).Tags))
+	}
+	g := pipeline.Show(key, "verbose", idxFile)
+	if g.Error.Err != nil {
+		t.Fatalf("want no error from Show, got %q", g.Error.Err)
+	}
+	buf := &bytes.Buffer{}
+	g.Output = buf
+	g.Stdout()
+	if !strings.Contains(buf.String(), "Tags: quality/test\n") {
+		t.Errorf("want renamed tag in the memo file, got %q", buf.String())
+	}
+	renamed, err = pipeline.RenameTag("test", "other", idxFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if renamed != 0 {
+		t.Errorf("want no memo renamed twice, got %d", renamed)
+	}
+}
+
+func TestRenameTagLegacy(t *testing.T) {
+	t.Parallel()
+	idxFile, _ := tempStore(t)
+	key := "01G7GVMW9R5Y3X8K7JCD0TQ2ZC"
+	addMemo(t, idxFile, key, []string{"K8S", "Ops"}, "Cluster upgrade\n")
+	renamed, err := pipeline.RenameTag("k8s", "kubernetes", idxFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if renamed != 1 {
+		t.Errorf("want 1 renamed memo, got %d", renamed)
+	}
+	idx := pipeline.NewIndex()
+	err = idx.Load(idxFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]bool{"kubernetes": true, "Ops": true}
+	if !cmp.Equal(want, idx.Find(key).Tags) {
+		t.Error(cmp.Diff(want, idx.Find(key).Tags))
+	}
+	fi, err := os.Open(filepath.Join(filepath.Dir(idxFile), key+".memo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fi.Close()
+	memo := pipeline.Memo{}
+	err = memo.Read(fi)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cmp.Equal([]string{"Ops", "kubernetes"}, memo.Tags) {
+		t.Error(cmp.Diff([]string{"Ops", "kubernetes"}, memo.Tags))
+	}
+}
+
+func TestAliasChains(t *testing.T) {
+	t.Parallel()
+	idxFile, _ := tempStore(t)
+	err := pipeline.SetAlias("k8s", "kubernetes", idxFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, alias := range [][2]string{{"kube", "k8s"}, {"kubernetes", "kube"}} {
+		err = pipeline.SetAlias(alias[0], alias[1], idxFile)
+		if err == nil {
+			t.Errorf("want error for %s as alias of %s, got none", alias[0], alias[1])
+		}
+	}
+	err = pipeline.SetAlias("kube", "kubernetes", idxFile)
+	if err != nil {
+		t.Errorf("want no error for a second synonym, got %q", err)
+	}
+	files, err := filepath.Glob(filepath.Join(filepath.Dir(idxFile), "config.json*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Errorf("want only the config file, got %q", files)
+	}
+}
+
+func TestAliases(t *testing.T) {
+	t.Parallel()
+	idxFile, _ := tempStore(t)
+	err := pipeline.SetAlias("K8S", "kubernetes", idxFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = pipeline.SetAlias("k8s", "k8s", idxFile)
+	if err == nil {
+		t.Error("want error for an alias of itself, got none")
+	}
+	cfg, err := pipeline.LoadConfig(idxFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := map[string]string{
+		"k8s":         "kubernetes",
+		"K8s":         "kubernetes",
+		"k8s/ops":     "kubernetes/ops",
+		"kubernetes":  "kubernetes",
+		"k8salike":    "k8salike",
+		"project/k8s": "project/k8s",
+	}
+	for tag, want := range tests {
+		got, err := pipeline.NormalizeTag(tag, cfg)
+		if err != nil {
+			t.Errorf("want no error for %q, got %q", tag, err)
+			continue
+		}
+		if want != got {
+			t.Errorf("want %q for %q, got %q", want, tag, got)
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+const (
+	termsFile = "terms.dat"
+
+	// BM25 saturation of term frequencies and normalisation of lengths
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// Lock for terms file, taken after the one for the index file
+var termsLock sync.Mutex
+
+// TermIndex ... an inverted index of memo contents, it maps terms to
+// the keys of the memos containing them and their positions within
+type TermIndex struct {
+	Terms   map[string]map[string][]int
+	Lengths map[string]int
+}
+
+// NewTermIndex ... Constructor for TermIndex
+func NewTermIndex() *TermIndex {
+	return &TermIndex{
+		Terms:   make(map[string]map[string][]int),
+		Lengths: make(map[string]int),
+	}
+}
+
+// termsPath ... the terms file next to the index file at filePath
+func termsPath(filePath string) string {
+	return filepath.Join(filepath.Dir(filePath), termsFile)
+}
+
+//////////////////////////////////////////////////////////
+// Reindex ... rebuilds the terms file from all memos of
+// the index, returns the number of indexed memos
+// used by reindex
+//////////////////////////////////////////////////////////
+func Reindex(filePath string) (int, error) {
+	if filePath == "" {
+		filePath = TakeMeHome(basePath + string(os.PathSeparator) + indexFile)
+	}
+	idx := NewIndex()
+	err := idx.Load(filePath)
+	if err != nil {
+		return 0, err
+	}
+	t := NewTermIndex()
+	for key, entry := range idx.entries {
+		memo := Memo{}
+		err := memo.load(entry.Path, key)
+		if err != nil {
+			return 0, err
+		}
+		t.Add(key, memo.Content)
+	}
+	termsLock.Lock()
+	defer termsLock.Unlock()
+	return len(t.Lengths), t.store(termsPath(filePath))
+}
+
+// Add ... indexes the content of the memo with key, an older content
+// gets replaced
+func (t *TermIndex) Add(key, content string) {
+	t.Remove(key)
+	words := terms(content)
+	for pos, term := range words {
+		postings, exist := t.Terms[term]
+		if !exist {
+			postings = make(map[string][]int)
+			t.Terms[term] = postings
+		}
+		postings[key] = append(postings[key], pos)
+	}
+	t.Lengths[key] = len(words)
+}
+
+// Remove ... drops the memo with key from the index
+func (t *TermIndex) Remove(key string) {
+	if _, exist := t.Lengths[key]; !exist {
+		return
+	}
+	for term, postings := range t.Terms {
+		delete(postings, key)
+		if len(postings) == 0 {
+			delete(t.Terms, term)
+		}
+	}
+	delete(t.Lengths, key)
+}
+
+// Has ... tells whether the memo with key is indexed
+func (t *TermIndex) Has(key string) bool {
+	_, exist := t.Lengths[key]
+	return exist
+}
+
+// Match ... tells whether the indexed memo with key satisfies the query
+func (t *TermIndex) Match(q SearchQuery, key string) bool {
+	for _, phrase := range q.Exclude {
+		if t.containsPhrase(key, phrase) {
+			return false
+		}
+	}
+	for _, phrase := range q.Include {
+		if !t.containsPhrase(key, phrase) {
+			return false
+		}
+	}
+	return true
+}
+
+// containsPhrase ... tells whether the terms of the phrase follow each
+// other within the memo with key
+func (t *TermIndex) containsPhrase(key string, phrase []string) bool {
+	for _, start := range t.Terms[phrase[0]][key] {
+		match := true
+		for j, term := range phrase[1:] {
+			positions := t.Terms[term][key]
+			n := sort.SearchInts(positions, start+j+1)
+			if n == len(positions) || positions[n] != start+j+1 {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+// Rank ... scores the stream entries by BM25 for the words and phrases
+// of the query and orders them, best first, all entries must be indexed
+func (t *TermIndex) Rank(q SearchQuery, s Stream) {
+	docs := float64(len(t.Lengths))
+	total := 0
+	for _, length := range t.Lengths {
+		total += length
+	}
+	avg := float64(total) / math.Max(docs, 1)
+	words := []string{}
+	seen := make(map[string]bool)
+	for _, phrase := range q.Include {
+		for _, term := range phrase {
+			if !seen[term] {
+				seen[term] = true
+				words = append(words, term)
+			}
+		}
+	}
+	for n := range s {
+		length := float64(t.Lengths[s[n].Key])
+		score := 0.0
+		for _, term := range words {
+			df := float64(len(t.Terms[term]))
+			tf := float64(len(t.Terms[term][s[n].Key]))
+			if tf == 0 {
+				continue
+			}
+			idf := math.Log(1 + (docs-df+0.5)/(df+0.5))
+			score += idf * tf * (bm25K1 + 1) / (tf + bm25K1*(1-bm25B+bm25B*length/math.Max(avg, 1)))
+		}
+		s[n].Score = score
+	}
+	s.Rank()
+}
+
+// Load ... Fills the terms from file at path
+func (t *TermIndex) Load(path string) error {
+	termsLock.Lock()
+	defer termsLock.Unlock()
+	return t.load(path)
+}
+
+// Update ... Loads the terms from file at path, changes them and stores
+// them again, all under the lock, nothing gets stored on errors
+func (t *TermIndex) Update(path string, change func(t *TermIndex) error) error {
+	termsLock.Lock()
+	defer termsLock.Unlock()
+	err := t.load(path)
+	if err != nil {
+		return err
+	}
+	err = change(t)
+	if err != nil {
+		return err
+	}
+	return t.store(path)
+}
+
+// store ... Writes a temporary file first and replaces the old one
+// afterwards, positions are many, so the JSON stays compact
+func (t *TermIndex) store(path string) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	err = f.Chmod(0o644)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	err = json.NewEncoder(f).Encode(t)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	err = f.Close()
+	if err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// load ... Reads the terms, a missing file is an empty index
+func (t *TermIndex) load(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+	err = json.NewDecoder(f).Decode(t)
+	if err != nil {
+		return fmt.Errorf("terms file %s is broken, try reindex: %w", path, err)
+	}
+	return nil
+}
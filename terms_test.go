@@ -0,0 +1,69 @@
+package pipeline_test
+
+import (
+	"os"
+	"path/filepath"
+	"pipeline"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestTermIndex(t *testing.T) {
+	t.Parallel()
+	ti := pipeline.NewTermIndex()
+	ti.Add("A", "The release plan, the plan")
+	ti.Add("B", "Plan the release")
+	want := map[string][]int{"A": {2, 4}, "B": {0}}
+	if !cmp.Equal(want, ti.Terms["plan"]) {
+		t.Error(cmp.Diff(want, ti.Terms["plan"]))
+	}
+	q, err := pipeline.ParseSearch(`"release plan" -draft`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ti.Match(q, "A") || ti.Match(q, "B") {
+		t.Error("want the phrase to match A only")
+	}
+	ti.Add("A", "Draft")
+	ti.Remove("B")
+	if ti.Has("B") || ti.Match(q, "A") {
+		t.Error("want B removed and A replaced")
+	}
+	if _, exist := ti.Terms["plan"]; exist {
+		t.Error("want no postings left for plan")
+	}
+}
+
+func TestReindex(t *testing.T) {
+	t.Parallel()
+	idxFile, key := tempStore(t)
+	addMemo(t, idxFile, "01G7H0000000000000000000C1", []string{"work"}, "Release plan\n")
+	indexed, err := pipeline.Reindex(idxFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if indexed != 2 {
+		t.Errorf("want 2 indexed memos, got %d", indexed)
+	}
+	// Search answers from the terms file alone
+	err = os.Remove(filepath.Join(filepath.Dir(idxFile), key+".memo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := pipeline.From(pipeline.PeriodAll, idxFile)
+	if f.Error.Err != nil {
+		t.Fatalf("want no error from From, got %q", f.Error.Err)
+	}
+	p := pipeline.Search(f.Reader, idxFile, "hello")
+	if p.Error.Err != nil {
+		t.Fatalf("want no error from Search, got %q", p.Error.Err)
+	}
+	found, err := pipeline.ReadStream(p.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 1 || found[0].Key != key {
+		t.Errorf("want %s found only, got %v", key, found)
+	}
+}
@@ -0,0 +1,144 @@
+package pipeline
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+type (
+	// document ... a memo as bag of words
+	document struct {
+		terms  map[string]int // term frequencies
+		length int            // number of terms
+		tags   []string
+	}
+
+	// corpus ... all memos of a store as bags of words
+	corpus struct {
+		docs map[string]document
+		df   map[string]int // number of documents per term
+	}
+
+	// neighbour ... a memo of the corpus and its similarity to another
+	neighbour struct {
+		key string
+		sim float64
+	}
+)
+
+// Words, which tell nothing about a memo
+var stopwords = map[string]bool{}
+
+func init() {
+	for _, word := range strings.Fields(`
+		a about after all also an and any are as at be because been but by can could
+		did do does for from had has have he her his how i if in into is it its just
+		me more my no not of on or our out she so some than that the their them then
+		there these they this to too up us was we were what when which who will with
+		would you your
+		aber als am an auch auf aus bei bin bis da das dass dem den der des die doch
+		du ein eine einem einen einer es für hat hatte ich ihr im in ist ja kann mit
+		nach nicht noch nur oder sich sie sind so über um und uns von vor war was
+		wie wir wird zu zum zur`) {
+		stopwords[word] = true
+	}
+}
+
+// terms ... splits a text into lower case words, in their order
+func terms(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// newDocument ... counts the terms of a text
+func newDocument(text string, tags []string) document {
+	doc := document{terms: make(map[string]int), tags: tags}
+	for _, term := range terms(text) {
+		doc.terms[term]++
+		doc.length++
+	}
+	return doc
+}
+
+// loadCorpus ... reads all memos of the index, which aren't in the trash
+func loadCorpus(idx *Index) (*corpus, error) {
+	c := &corpus{docs: make(map[string]document), df: make(map[string]int)}
+	for key, entry := range idx.entries {
+		if entry.Deleted != "" {
+			continue
+		}
+		memo := Memo{}
+		err := memo.load(entry.Path, key)
+		if err != nil {
+			return nil, err
+		}
+		c.add(key, newDocument(memo.Content, memo.Tags))
+	}
+	return c, nil
+}
+
+// add ... puts a document into the corpus
+func (c *corpus) add(key string, doc document) {
+	c.docs[key] = doc
+	for term := range doc.terms {
+		c.df[term]++
+	}
+}
+
+// idf ... the inverse document frequency of a term, terms unknown
+// to the corpus weigh most
+func (c *corpus) idf(term string) float64 {
+	return math.Log(float64(len(c.docs)+1)/float64(c.df[term]+1)) + 1
+}
+
+// weights ... the TF-IDF vector of a document, stopwords weigh nothing
+func (c *corpus) weights(doc document) map[string]float64 {
+	w := make(map[string]float64, len(doc.terms))
+	for term, tf := range doc.terms {
+		if stopwords[term] {
+			continue
+		}
+		w[term] = float64(tf) / float64(doc.length) * c.idf(term)
+	}
+	return w
+}
+
+// neighbours ... the memos similar to the weights, most similar first,
+// the memo with key skip left out
+func (c *corpus) neighbours(weights map[string]float64, skip string) []neighbour {
+	neighbours := []neighbour{}
+	for key, doc := range c.docs {
+		if key == skip {
+			continue
+		}
+		if sim := cosine(weights, c.weights(doc)); sim > 0 {
+			neighbours = append(neighbours, neighbour{key, sim})
+		}
+	}
+	sort.Slice(neighbours, func(i, j int) bool {
+		if neighbours[i].sim != neighbours[j].sim {
+			return neighbours[i].sim > neighbours[j].sim
+		}
+		return neighbours[i].key < neighbours[j].key
+	})
+	return neighbours
+}
+
+// cosine ... the similarity of two weight vectors between 0 and 1
+func cosine(a, b map[string]float64) float64 {
+	var dot, normA, normB float64
+	for term, wa := range a {
+		dot += wa * b[term]
+		normA += wa * wa
+	}
+	for _, wb := range b {
+		normB += wb * wb
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(normA*normB)
+}